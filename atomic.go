@@ -0,0 +1,133 @@
+package fileutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes data to the file at path, replacing it atomically.
+// Readers see either the old or the new content, never a partial write, see AtomicWriter for details.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	w, err := NewAtomicWriter(path, perm)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = w.Abort() }() // no-op after a successful commit
+
+	if _, err = w.Write(data); err != nil {
+		return err
+	}
+	return w.Commit()
+}
+
+// AtomicWriter writes a file through a temporary file in the same directory,
+// renamed over the target on Commit, so the target is never seen partially written.
+// The file and its directory are synced, so the new content survives a crash once Commit returns.
+// Close is the same as Commit, Abort drops the written data and is a no-op after a commit,
+// which makes a deferred Abort the usual way to clean up on error paths.
+type AtomicWriter struct {
+	path string
+	f    *os.File
+	done bool
+}
+
+// NewAtomicWriter starts writing a new version of the file at path.
+// If the file exists, its mode and, as far as the process is permitted to set it, ownership carry over,
+// otherwise the new file gets perm, filtered by umask. A symlink at path is replaced, not followed,
+// the new file gets perm as well.
+func NewAtomicWriter(path string, perm os.FileMode) (*AtomicWriter, error) {
+	if path == "" {
+		return nil, errors.New("empty path")
+	}
+
+	info, err := os.Lstat(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if err == nil && info.Mode()&os.ModeSymlink != 0 {
+		info = nil // the link itself is replaced, its target's attributes don't apply
+	}
+	if info != nil && !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("can't replace non-regular file %s (%s)", path, info.Mode().String())
+	}
+
+	f, err := createTempSibling(path, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	if info != nil {
+		if err = copyFileAttrs(f, info); err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+			return nil, fmt.Errorf("failed to preserve attributes of %s: %w", path, err)
+		}
+	}
+
+	return &AtomicWriter{path: path, f: f}, nil
+}
+
+// copyFileAttrs gives f the mode and the owner of the file described by info.
+// Chown goes first as it clears setuid and setgid bits, and failing to hand the file
+// to another owner is expected for unprivileged processes, so it is not an error.
+func copyFileAttrs(f *os.File, info os.FileInfo) error {
+	if uid, gid, ok := fileOwner(info); ok {
+		if err := f.Chown(uid, gid); err != nil && !errors.Is(err, os.ErrPermission) {
+			return err
+		}
+	}
+	return f.Chmod(info.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky))
+}
+
+// Write writes p to the temporary file
+func (w *AtomicWriter) Write(p []byte) (int, error) {
+	if w.done {
+		return 0, fmt.Errorf("atomic writer for %s is closed", w.path)
+	}
+	return w.f.Write(p)
+}
+
+// Commit syncs the written data and renames it over the target file
+func (w *AtomicWriter) Commit() error {
+	if w.done {
+		return fmt.Errorf("atomic writer for %s is closed", w.path)
+	}
+	w.done = true
+	tmpName := w.f.Name()
+
+	if err := w.f.Sync(); err != nil {
+		_ = w.f.Close()
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("failed to sync %s: %w", tmpName, err)
+	}
+	if err := w.f.Close(); err != nil {
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("failed to close %s: %w", tmpName, err)
+	}
+	if err := os.Rename(tmpName, w.path); err != nil {
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("failed to replace %s: %w", w.path, err)
+	}
+
+	return syncDir(filepath.Dir(w.path))
+}
+
+// Close commits the file, see Commit
+func (w *AtomicWriter) Close() error {
+	return w.Commit()
+}
+
+// Abort discards the written data and leaves the target file as it was
+func (w *AtomicWriter) Abort() error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+	_ = w.f.Close()
+	if err := os.Remove(w.f.Name()); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", w.f.Name(), err)
+	}
+	return nil
+}
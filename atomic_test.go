@@ -0,0 +1,151 @@
+package fileutils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	t.Run("new file", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "config.json")
+		require.NoError(t, WriteFileAtomic(path, []byte(`{"a":1}`), 0o600))
+
+		content, err := os.ReadFile(path) //nolint:gosec
+		require.NoError(t, err)
+		assert.Equal(t, `{"a":1}`, string(content))
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+		assertOnlyFiles(t, dir, "config.json")
+	})
+
+	t.Run("existing file keeps its mode", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "config.json")
+		require.NoError(t, os.WriteFile(path, []byte("old"), 0o600))
+		require.NoError(t, os.Chmod(path, 0o640)) // explicit chmod, WriteFile is subject to umask
+
+		require.NoError(t, WriteFileAtomic(path, []byte("new"), 0o600))
+
+		content, err := os.ReadFile(path) //nolint:gosec
+		require.NoError(t, err)
+		assert.Equal(t, "new", string(content))
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+		assertOnlyFiles(t, dir, "config.json")
+	})
+
+	t.Run("existing file keeps its owner", func(t *testing.T) {
+		if os.Geteuid() != 0 {
+			t.Skip("only root can hand a file to another owner")
+		}
+		path := filepath.Join(t.TempDir(), "config.json")
+		require.NoError(t, os.WriteFile(path, []byte("old"), 0o600))
+		require.NoError(t, os.Chown(path, 1234, 5678))
+
+		require.NoError(t, WriteFileAtomic(path, []byte("new"), 0o600))
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		uid, gid, ok := fileOwner(info)
+		require.True(t, ok)
+		assert.Equal(t, 1234, uid)
+		assert.Equal(t, 5678, gid)
+	})
+
+	t.Run("symlink is replaced", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("symlinks need privileges on windows")
+		}
+		dir := t.TempDir()
+		target, link := filepath.Join(dir, "target.txt"), filepath.Join(dir, "link.txt")
+		require.NoError(t, os.WriteFile(target, []byte("target"), 0o600))
+		require.NoError(t, os.Chmod(target, 0o640))
+		require.NoError(t, os.Symlink("target.txt", link))
+		require.NoError(t, WriteFileAtomic(link, []byte("new"), 0o600))
+
+		info, err := os.Lstat(link)
+		require.NoError(t, err)
+		assert.True(t, info.Mode().IsRegular())
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "the mode of the target is not copied")
+		content, err := os.ReadFile(target) //nolint:gosec
+		require.NoError(t, err)
+		assert.Equal(t, "target", string(content))
+
+		// a symlink to a directory is replaced the same way
+		require.NoError(t, os.Symlink(t.TempDir(), filepath.Join(dir, "dir-link")))
+		require.NoError(t, WriteFileAtomic(filepath.Join(dir, "dir-link"), []byte("new"), 0o600))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		err := WriteFileAtomic("", []byte("data"), 0o600)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "empty path")
+
+		err = WriteFileAtomic(t.TempDir(), []byte("data"), 0o600)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "non-regular file")
+
+		err = WriteFileAtomic(filepath.Join(t.TempDir(), "missing", "file.txt"), []byte("data"), 0o600)
+		require.Error(t, err)
+	})
+}
+
+func TestAtomicWriter(t *testing.T) {
+	t.Run("commit on close", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "state.txt")
+		require.NoError(t, os.WriteFile(path, []byte("old"), 0o600))
+
+		w, err := NewAtomicWriter(path, 0o600)
+		require.NoError(t, err)
+		_, err = w.Write([]byte("new "))
+		require.NoError(t, err)
+		_, err = w.Write([]byte("state"))
+		require.NoError(t, err)
+
+		// nothing visible before the commit
+		content, err := os.ReadFile(path) //nolint:gosec
+		require.NoError(t, err)
+		assert.Equal(t, "old", string(content))
+
+		require.NoError(t, w.Close())
+		content, err = os.ReadFile(path) //nolint:gosec
+		require.NoError(t, err)
+		assert.Equal(t, "new state", string(content))
+		assertOnlyFiles(t, dir, "state.txt")
+
+		// the writer is finished now, aborting is a no-op and anything else fails
+		require.NoError(t, w.Abort())
+		_, err = w.Write([]byte("more"))
+		require.Error(t, err)
+		require.Error(t, w.Commit())
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "state.txt")
+		require.NoError(t, os.WriteFile(path, []byte("old"), 0o600))
+
+		w, err := NewAtomicWriter(path, 0o600)
+		require.NoError(t, err)
+		_, err = w.Write([]byte("discarded"))
+		require.NoError(t, err)
+		require.NoError(t, w.Abort())
+
+		content, err := os.ReadFile(path) //nolint:gosec
+		require.NoError(t, err)
+		assert.Equal(t, "old", string(content))
+		assertOnlyFiles(t, dir, "state.txt")
+		require.Error(t, w.Commit())
+	})
+}
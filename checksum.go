@@ -0,0 +1,235 @@
+package fileutils
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+// ChecksumReader calculates the checksum of everything read from r until EOF.
+// Supported algorithms are the same as for Checksum.
+func ChecksumReader(r io.Reader, algo enum.HashAlg) (string, error) {
+	h, err := newHash(algo)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("failed to read data for hashing: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// MultiChecksum calculates the checksums of a file with several hash algorithms, reading the file only once.
+// Returns the checksums keyed by algorithm.
+func MultiChecksum(path string, algos ...enum.HashAlg) (map[enum.HashAlg]string, error) {
+	cw, err := NewChecksumWriter(io.Discard, algos...)
+	if err != nil {
+		return nil, err
+	}
+	if err := hashFileInto(path, cw); err != nil {
+		return nil, err
+	}
+	return cw.Sums(), nil
+}
+
+// MultiChecksumByName is MultiChecksum with the hash algorithms given by name, see HashByName.
+// Returns the checksums keyed by name as given.
+func MultiChecksumByName(path string, names ...string) (map[string]string, error) {
+	cw, err := NewChecksumWriterByName(io.Discard, names...)
+	if err != nil {
+		return nil, err
+	}
+	if err := hashFileInto(path, cw); err != nil {
+		return nil, err
+	}
+	return cw.SumsByName(), nil
+}
+
+// hashFileInto reads the file at path into cw
+func hashFileInto(path string, cw *ChecksumWriter) error {
+	if path == "" {
+		return errors.New("empty path")
+	}
+
+	f, err := openChecksumFile(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err := io.Copy(cw, f); err != nil {
+		return fmt.Errorf("failed to read file %s for hashing: %w", path, err)
+	}
+	return nil
+}
+
+// ChecksumWriter passes everything written to it on to another writer, calculating checksums of the data
+// on the way, so a copy can be hashed without reading the data again. Typically used with io.Copy or io.TeeReader.
+type ChecksumWriter struct {
+	w      io.Writer
+	hashes map[string]hash.Hash    // keyed by algorithm name
+	algos  map[string]enum.HashAlg // built-in algorithms, keyed by name
+}
+
+// NewChecksumWriter makes a ChecksumWriter writing to w and hashing with every given algorithm
+func NewChecksumWriter(w io.Writer, algos ...enum.HashAlg) (*ChecksumWriter, error) {
+	names := make([]string, len(algos))
+	for i, algo := range algos {
+		if _, err := newHash(algo); err != nil {
+			return nil, err
+		}
+		names[i] = algo.String()
+	}
+	return NewChecksumWriterByName(w, names...)
+}
+
+// NewChecksumWriterByName makes a ChecksumWriter writing to w and hashing with every algorithm given by name,
+// see HashByName
+func NewChecksumWriterByName(w io.Writer, names ...string) (*ChecksumWriter, error) {
+	if w == nil {
+		return nil, errors.New("writer is required")
+	}
+	if len(names) == 0 {
+		return nil, errors.New("no hash algorithm")
+	}
+
+	cw := &ChecksumWriter{w: w, hashes: make(map[string]hash.Hash, len(names)), algos: map[string]enum.HashAlg{}}
+	for _, name := range names {
+		h, err := HashByName(name)
+		if err != nil {
+			return nil, err
+		}
+		cw.hashes[name] = h
+		if algo, err := enum.ParseHashAlg(name); err == nil {
+			cw.algos[name] = algo
+		}
+	}
+	return cw, nil
+}
+
+// Write writes p to the underlying writer and adds the part written to the checksums
+func (cw *ChecksumWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	for _, h := range cw.hashes {
+		_, _ = h.Write(p[:n]) // hash.Hash never fails to write
+	}
+	return n, err
+}
+
+// Sums returns the checksums of the data written so far with built-in algorithms, keyed by algorithm
+func (cw *ChecksumWriter) Sums() map[enum.HashAlg]string {
+	res := make(map[enum.HashAlg]string, len(cw.algos))
+	for name, algo := range cw.algos {
+		res[algo] = hex.EncodeToString(cw.hashes[name].Sum(nil))
+	}
+	return res
+}
+
+// SumsByName returns the checksums of the data written so far with all algorithms, keyed by name
+// as given to NewChecksumWriterByName, or as enum.HashAlg.String for NewChecksumWriter
+func (cw *ChecksumWriter) SumsByName() map[string]string {
+	res := make(map[string]string, len(cw.hashes))
+	for name, h := range cw.hashes {
+		res[name] = hex.EncodeToString(h.Sum(nil))
+	}
+	return res
+}
+
+// hashRegistry holds the hash algorithms registered with RegisterHash, keyed by lowercase name
+var hashRegistry = struct {
+	sync.RWMutex
+	hashes map[string]func() hash.Hash
+}{hashes: map[string]func() hash.Hash{}}
+
+// RegisterHash makes a hash algorithm, like BLAKE3 or xxHash, available under name to ChecksumByName,
+// HashByName and everything else taking an algorithm by name. Names are case-insensitive, can't contain
+// whitespace or parentheses, and can't be registered twice or clash with the names of enum.HashAlg,
+// which are always available.
+func RegisterHash(name string, fn func() hash.Hash) error {
+	if name == "" {
+		return errors.New("empty hash algorithm name")
+	}
+	if strings.ContainsAny(name, " \t\r\n()") {
+		return fmt.Errorf("invalid hash algorithm name %q", name)
+	}
+	if fn == nil {
+		return errors.New("hash constructor is required")
+	}
+	if _, err := enum.ParseHashAlg(name); err == nil {
+		return fmt.Errorf("hash algorithm %s is built in", name)
+	}
+
+	hashRegistry.Lock()
+	defer hashRegistry.Unlock()
+	key := strings.ToLower(name)
+	if _, ok := hashRegistry.hashes[key]; ok {
+		return fmt.Errorf("hash algorithm %s is already registered", name)
+	}
+	hashRegistry.hashes[key] = fn
+	return nil
+}
+
+// HashByName returns a new hash.Hash for the algorithm with the given name,
+// either a built-in one named as in enum.HashAlg or one registered with RegisterHash
+func HashByName(name string) (hash.Hash, error) {
+	if algo, err := enum.ParseHashAlg(name); err == nil {
+		return newHash(algo)
+	}
+
+	hashRegistry.RLock()
+	fn, ok := hashRegistry.hashes[strings.ToLower(name)]
+	hashRegistry.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported hash algorithm: %s", name)
+	}
+	return fn(), nil
+}
+
+// ChecksumByName is Checksum with the hash algorithm given by name, see HashByName
+func ChecksumByName(path, name string) (string, error) {
+	if path == "" {
+		return "", errors.New("empty path")
+	}
+
+	h, err := HashByName(name)
+	if err != nil {
+		return "", err
+	}
+	return checksumFile(path, h)
+}
+
+// CopyFileVerified copies src to dst the way CopyFile does, then checks the copy by comparing checksums
+// of both files calculated with the algorithm given by name, see HashByName. Returns the checksum.
+func CopyFileVerified(src, dst, name string) (string, error) {
+	if _, err := HashByName(name); err != nil {
+		return "", err
+	}
+	if err := CopyFile(src, dst); err != nil {
+		return "", err
+	}
+
+	srcSum, err := ChecksumByName(src, name)
+	if err != nil {
+		return "", err
+	}
+	dstSum, err := ChecksumByName(dst, name)
+	if err != nil {
+		return "", err
+	}
+	if srcSum != dstSum {
+		return "", fmt.Errorf("checksum mismatch after copy: source %s, destination %s", srcSum, dstSum)
+	}
+	return srcSum, nil
+}
+
+// checksumFile hashes the content of the file at path with h, returning the hex encoded checksum
+func checksumFile(path string, h hash.Hash) (string, error) {
+	return checksumFileContext(context.Background(), path, h)
+}
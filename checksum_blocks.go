@@ -0,0 +1,189 @@
+package fileutils
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/bits"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+// DefaultBlockSize is the block size ChecksumBlocks uses if no size is given
+const DefaultBlockSize = 64 * 1024
+
+// MaxBlockSize is the largest block ChecksumBlocks makes, as a block is read into memory whole.
+// It limits the size of fixed blocks, and the average size of content-defined chunks to a quarter of it.
+const MaxBlockSize = 16 * 1024 * 1024
+
+// Block is a part of a file with its checksum
+type Block struct {
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+	Sum    string `json:"sum"`
+}
+
+// ByteRange is a range of bytes in a file
+type ByteRange struct {
+	Offset int64 `json:"offset"`
+	Length int64 `json:"length"`
+}
+
+// BlockOptions controls ChecksumBlocks
+type BlockOptions struct {
+	// Size is the size of fixed blocks, or the average size of content-defined chunks, DefaultBlockSize if zero
+	Size int64
+	// ContentDefined splits the file at positions chosen by a rolling hash of the content instead of fixed offsets,
+	// so bytes inserted or removed in one place change only the chunks around it, not all the following ones.
+	// Chunks are between a quarter and four times the average size.
+	ContentDefined bool
+}
+
+// ChecksumBlocks splits a file into blocks and calculates the checksum of each, e.g. to find out which parts
+// of a file changed since an older version with DiffBlocks. An empty file has no blocks.
+func ChecksumBlocks(path string, algo enum.HashAlg, opts BlockOptions) ([]Block, error) {
+	if path == "" {
+		return nil, errors.New("empty path")
+	}
+	if opts.Size < 0 {
+		return nil, fmt.Errorf("invalid block size %d", opts.Size)
+	}
+	if opts.Size == 0 {
+		opts.Size = DefaultBlockSize
+	}
+	if opts.ContentDefined && opts.Size < 64 {
+		return nil, fmt.Errorf("average chunk size %d is too small, at least 64 bytes required", opts.Size)
+	}
+	if opts.ContentDefined && opts.Size > MaxBlockSize/4 {
+		return nil, fmt.Errorf("average chunk size %d is too large, at most %d bytes allowed", opts.Size, MaxBlockSize/4)
+	}
+	if opts.Size > MaxBlockSize {
+		return nil, fmt.Errorf("block size %d is too large, at most %d bytes allowed", opts.Size, MaxBlockSize)
+	}
+
+	h, err := newHash(algo)
+	if err != nil {
+		return nil, err
+	}
+	f, err := openChecksumFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var res []Block
+	if opts.ContentDefined {
+		res, err = chunkBlocks(bufio.NewReader(f), h, opts.Size)
+	} else {
+		res, err = fixedBlocks(f, h, opts.Size)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s for hashing: %w", path, err)
+	}
+	return res, nil
+}
+
+// DiffBlocks compares blocks of the current version of a file with blocks of an older one, both made by ChecksumBlocks
+// with the same algorithm and options, and returns the sorted ranges of the current version with content not found
+// anywhere in the old one, merging adjacent changed blocks. Blocks which only moved are not reported,
+// so the ranges are what a delta transfer has to send, while the rest can be copied from the old version.
+func DiffBlocks(old, cur []Block) []ByteRange {
+	type blockKey struct {
+		size int64
+		sum  string
+	}
+	known := make(map[blockKey]bool, len(old))
+	for _, b := range old {
+		known[blockKey{size: b.Size, sum: b.Sum}] = true
+	}
+
+	var res []ByteRange
+	for _, b := range cur {
+		if known[blockKey{size: b.Size, sum: b.Sum}] {
+			continue
+		}
+		if n := len(res); n > 0 && res[n-1].Offset+res[n-1].Length == b.Offset {
+			res[n-1].Length += b.Size
+			continue
+		}
+		res = append(res, ByteRange{Offset: b.Offset, Length: b.Size})
+	}
+	return res
+}
+
+// fixedBlocks hashes blocks of size bytes read from r, the last one can be shorter
+func fixedBlocks(r io.Reader, h hash.Hash, size int64) ([]Block, error) {
+	var res []Block
+	buf := make([]byte, size)
+	for offset := int64(0); ; {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			res = append(res, newBlock(h, offset, buf[:n]))
+			offset += int64(n)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// chunkBlocks hashes content-defined chunks read from r. A chunk ends where the gear hash of the bytes read
+// since its start has the top bits set to zero, which happens once every avgSize bytes on average,
+// or when it reaches the maximal size. The hash depends only on the last 64 bytes, so after a change
+// the chunk boundaries get back in sync with the old ones.
+func chunkBlocks(r io.ByteReader, h hash.Hash, avgSize int64) ([]Block, error) {
+	minSize, maxSize := avgSize/4, avgSize*4
+	shift := uint(64 - (bits.Len64(uint64(avgSize)) - 1))
+
+	var res []Block
+	var offset int64
+	var gear uint64
+	buf := make([]byte, 0, maxSize)
+	for {
+		c, err := r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, c)
+		gear = gear<<1 + gearTable[c]
+		if (int64(len(buf)) >= minSize && gear>>shift == 0) || int64(len(buf)) >= maxSize {
+			res = append(res, newBlock(h, offset, buf))
+			offset += int64(len(buf))
+			buf, gear = buf[:0], 0
+		}
+	}
+	if len(buf) > 0 {
+		res = append(res, newBlock(h, offset, buf))
+	}
+	return res, nil
+}
+
+// newBlock makes a block of data at offset, reusing h for the checksum
+func newBlock(h hash.Hash, offset int64, data []byte) Block {
+	h.Reset()
+	_, _ = h.Write(data)
+	return Block{Offset: offset, Size: int64(len(data)), Sum: hex.EncodeToString(h.Sum(nil))}
+}
+
+// gearTable maps bytes to random values for the gear hash of chunkBlocks. The values come from splitmix64
+// with a fixed seed, chunk boundaries and so block lists depend on them and have to be the same for every run.
+var gearTable = func() (res [256]uint64) {
+	seed := uint64(0x5eed)
+	for i := range res {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		res[i] = z ^ (z >> 31)
+	}
+	return res
+}()
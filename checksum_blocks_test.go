@@ -0,0 +1,131 @@
+package fileutils
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+func TestChecksumBlocks(t *testing.T) {
+	path := writeChecksumTestFile(t)
+
+	blocks, err := ChecksumBlocks(path, enum.HashAlgMD5, BlockOptions{Size: 16})
+	require.NoError(t, err)
+	require.Len(t, blocks, 3)
+	for i, b := range blocks {
+		want, err := ChecksumRange(path, enum.HashAlgMD5, b.Offset, b.Size)
+		require.NoError(t, err)
+		assert.Equal(t, want, b.Sum, "block %d", i)
+		assert.Equal(t, int64(i*16), b.Offset)
+	}
+	assert.Equal(t, int64(12), blocks[2].Size)
+
+	blocks, err = ChecksumBlocks(path, enum.HashAlgSHA256, BlockOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []Block{{Offset: 0, Size: int64(len(checksumTestContent)), Sum: checksumTestSHA256}}, blocks)
+
+	empty := filepath.Join(t.TempDir(), "empty")
+	require.NoError(t, os.WriteFile(empty, nil, 0o600))
+	for _, opts := range []BlockOptions{{}, {ContentDefined: true}} {
+		blocks, err = ChecksumBlocks(empty, enum.HashAlgSHA256, opts)
+		require.NoError(t, err)
+		assert.Empty(t, blocks)
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		tbl := []struct {
+			path    string
+			algo    enum.HashAlg
+			opts    BlockOptions
+			wantErr string
+		}{
+			{"", enum.HashAlgSHA256, BlockOptions{}, "empty path"},
+			{path, enum.HashAlgSHA256, BlockOptions{Size: -1}, "invalid block size"},
+			{path, enum.HashAlgSHA256, BlockOptions{Size: 32, ContentDefined: true}, "too small"},
+			{path, enum.HashAlgSHA256, BlockOptions{Size: MaxBlockSize + 1}, "block size 16777217 is too large"},
+			{path, enum.HashAlgSHA256, BlockOptions{Size: 1 << 62, ContentDefined: true}, "average chunk size"},
+			{path, enum.HashAlgSHA256, BlockOptions{Size: MaxBlockSize/4 + 1, ContentDefined: true}, "too large"},
+			{path, enum.HashAlg{}, BlockOptions{}, "unsupported hash algorithm"},
+			{"nonexistent.txt", enum.HashAlgSHA256, BlockOptions{}, "file not found"},
+			{filepath.Dir(path), enum.HashAlgSHA256, BlockOptions{}, "failed to read file"},
+		}
+		for _, tt := range tbl {
+			_, err := ChecksumBlocks(tt.path, tt.algo, tt.opts)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		}
+	})
+}
+
+func TestDiffBlocks(t *testing.T) {
+	dir := t.TempDir()
+	blocksOf := func(content []byte, opts BlockOptions) []Block {
+		path := filepath.Join(dir, "data.bin")
+		require.NoError(t, os.WriteFile(path, content, 0o600))
+		blocks, err := ChecksumBlocks(path, enum.HashAlgSHA256, opts)
+		require.NoError(t, err)
+		return blocks
+	}
+
+	t.Run("fixed blocks", func(t *testing.T) {
+		opts := BlockOptions{Size: 10}
+		content := []byte(strings.Repeat("a", 10) + strings.Repeat("b", 10) + strings.Repeat("c", 10) + "dddd")
+		old := blocksOf(content, opts)
+		assert.Empty(t, DiffBlocks(old, old))
+
+		changed := append([]byte(nil), content...)
+		changed[12], changed[25] = 'x', 'x'
+		assert.Equal(t, []ByteRange{{Offset: 10, Length: 20}}, DiffBlocks(old, blocksOf(changed, opts)))
+
+		changed = append(append([]byte(nil), content...), []byte("eeeee")...)
+		assert.Equal(t, []ByteRange{{Offset: 30, Length: 9}}, DiffBlocks(old, blocksOf(changed, opts)))
+
+		// a moved block is found in the old version
+		changed = append(append([]byte(nil), content[10:30]...), content[:10]...)
+		assert.Empty(t, DiffBlocks(old, blocksOf(changed, opts)))
+
+		assert.Equal(t, []ByteRange{{Offset: 0, Length: 34}}, DiffBlocks(nil, old))
+	})
+
+	t.Run("content defined chunks", func(t *testing.T) {
+		const avgSize = 1024
+		opts := BlockOptions{Size: avgSize, ContentDefined: true}
+		content := make([]byte, 256*1024)
+		rnd := rand.New(rand.NewSource(42)) //nolint:gosec // deterministic test data
+		_, _ = rnd.Read(content)
+
+		old := blocksOf(content, opts)
+		assert.Greater(t, len(old), 256/4, "about one chunk per average size expected")
+		var total int64
+		for i, b := range old {
+			assert.Equal(t, total, b.Offset)
+			total += b.Size
+			assert.LessOrEqual(t, b.Size, int64(4*avgSize))
+			if i < len(old)-1 {
+				assert.GreaterOrEqual(t, b.Size, int64(avgSize/4))
+			}
+		}
+		assert.Equal(t, int64(len(content)), total)
+
+		// bytes inserted in the middle change only the chunks around them, unlike with fixed blocks
+		const at = 100 * 1024
+		changed := append(append(append([]byte(nil), content[:at]...), []byte("inserted")...), content[at:]...)
+		ranges := DiffBlocks(old, blocksOf(changed, opts))
+		require.Len(t, ranges, 1)
+		assert.LessOrEqual(t, ranges[0].Offset, int64(at))
+		assert.GreaterOrEqual(t, ranges[0].Offset+ranges[0].Length, int64(at+len("inserted")))
+		assert.Less(t, ranges[0].Length, int64(3*4*avgSize))
+
+		fixedOpts := BlockOptions{Size: avgSize}
+		fixedRanges := DiffBlocks(blocksOf(content, fixedOpts), blocksOf(changed, fixedOpts))
+		require.Len(t, fixedRanges, 1)
+		assert.Equal(t, int64(len(changed)-at), fixedRanges[0].Length, "everything after the insertion changed")
+	})
+}
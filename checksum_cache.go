@@ -0,0 +1,230 @@
+package fileutils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+// checksumCacheFile is the name of the index file in the cache directory
+const checksumCacheFile = "checksums.json"
+
+// checksumCacheLock is the name of the lock file in the cache directory, held while the index is saved
+const checksumCacheLock = "checksums.lock"
+
+// checksumCacheSaveMu serializes saves within the process, which file locks don't do on unix
+var checksumCacheSaveMu sync.Mutex
+
+// checksumCacheVersion is the version of the index format, an index of another version is discarded
+const checksumCacheVersion = 1
+
+// ChecksumCache keeps checksums of files between runs, so unchanged files are not read again.
+// A file is considered unchanged while its device, inode, size, modification and change times stay the same,
+// change of any of them invalidates its cached checksums. Device, inode and change time are used on unix only.
+// The cache is safe for concurrent use, also by several processes sharing a cache directory. It is loaded
+// by NewChecksumCache and written back by Save only, which merges the changes made since the last Save
+// with the index saved by others meanwhile.
+type ChecksumCache struct {
+	path string // index file
+	lock string // lock file
+
+	mu      sync.Mutex
+	entries map[string]checksumCacheEntry // by absolute path of the file
+	changed map[string]bool               // entries added or updated since the last Save
+	pruned  map[string]fileKey            // entries dropped by Prune since the last Save
+}
+
+// fileKey is what tells a changed file from an unchanged one without reading it
+type fileKey struct {
+	Dev   uint64 `json:"dev,omitempty"`
+	Ino   uint64 `json:"ino,omitempty"`
+	Size  int64  `json:"size"`
+	MTime int64  `json:"mtime"`           // in nanoseconds
+	CTime int64  `json:"ctime,omitempty"` // in nanoseconds
+}
+
+// checksumCacheEntry is the cached state of a single file
+type checksumCacheEntry struct {
+	fileKey
+	Sums map[string]string `json:"sums"` // hex encoded checksums by algorithm name
+}
+
+// checksumCacheIndex is the content of the index file
+type checksumCacheIndex struct {
+	Version int                           `json:"version"`
+	Entries map[string]checksumCacheEntry `json:"entries"`
+}
+
+// NewChecksumCache makes a cache stored in dir, creating the directory if needed and loading the index
+// saved there before. A missing index, or an unreadable one, e.g. written by another version, gives an empty cache.
+func NewChecksumCache(dir string) (*ChecksumCache, error) {
+	if dir == "" {
+		return nil, errors.New("empty path")
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory %s: %w", dir, err)
+	}
+
+	res := &ChecksumCache{path: filepath.Join(dir, checksumCacheFile), lock: filepath.Join(dir, checksumCacheLock),
+		changed: map[string]bool{}, pruned: map[string]fileKey{}}
+	entries, err := readChecksumIndex(res.path)
+	if err != nil {
+		return nil, err
+	}
+	res.entries = entries
+	return res, nil
+}
+
+// readChecksumIndex reads the entries of the index file at path, none if it's missing or unreadable
+func readChecksumIndex(path string) (map[string]checksumCacheEntry, error) {
+	res := map[string]checksumCacheEntry{}
+	data, err := os.ReadFile(path) //nolint:gosec // index file in a directory given by the caller
+	if err != nil {
+		if os.IsNotExist(err) {
+			return res, nil
+		}
+		return nil, fmt.Errorf("failed to read checksum cache %s: %w", path, err)
+	}
+	var index checksumCacheIndex
+	if err := json.Unmarshal(data, &index); err != nil || index.Version != checksumCacheVersion {
+		return res, nil // the cache is rebuilt from scratch
+	}
+	if index.Entries != nil {
+		res = index.Entries
+	}
+	return res, nil
+}
+
+// Checksum calculates a file checksum the way the package level Checksum does, returning the cached one
+// if the file is unchanged since it was calculated. A checksum is cached only if the file didn't change
+// while being read.
+func (c *ChecksumCache) Checksum(path string, algo enum.HashAlg) (string, error) {
+	if path == "" {
+		return "", errors.New("empty path")
+	}
+	h, err := newHash(algo)
+	if err != nil {
+		return "", err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("can't get absolute path of %s: %w", path, err)
+	}
+	key, err := fileStatKey(absPath)
+	if err != nil {
+		return checksumFile(path, h) // reports a missing file the same way as uncached checksums
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[absPath]
+	sum, found := entry.Sums[algo.String()]
+	c.mu.Unlock()
+	if ok && found && entry.fileKey == key {
+		return sum, nil
+	}
+
+	sum, err = checksumFile(path, h)
+	if err != nil {
+		return "", err
+	}
+	if after, err := fileStatKey(absPath); err != nil || after != key {
+		return sum, nil // changed while being read, the checksum may not match the key
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok = c.entries[absPath]
+	if !ok || entry.fileKey != key {
+		entry = checksumCacheEntry{fileKey: key, Sums: map[string]string{}}
+	}
+	entry.Sums[algo.String()] = sum
+	c.entries[absPath] = entry
+	c.changed[absPath] = true
+	return sum, nil
+}
+
+// Prune drops entries of files which are gone or changed, returning the number of dropped entries.
+// Entries are never dropped otherwise, so a cache shared by changing trees grows until pruned.
+func (c *ChecksumCache) Prune() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var res int
+	for path, entry := range c.entries {
+		if key, err := fileStatKey(path); err != nil || key != entry.fileKey {
+			delete(c.entries, path)
+			delete(c.changed, path)
+			c.pruned[path] = entry.fileKey
+			res++
+		}
+	}
+	return res
+}
+
+// Save writes the index to the cache directory atomically, if anything changed since it was loaded or saved.
+// Holding a lock file, it re-reads the index, applies the changes made since the last Save to it and writes
+// the result, so entries saved by other processes meanwhile are kept, and picked up by this cache as well.
+func (c *ChecksumCache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.changed) == 0 && len(c.pruned) == 0 {
+		return nil
+	}
+
+	checksumCacheSaveMu.Lock()
+	defer checksumCacheSaveMu.Unlock()
+	unlock, err := lockFile(c.lock)
+	if err != nil {
+		return fmt.Errorf("failed to save checksum cache: %w", err)
+	}
+	defer unlock()
+
+	entries, err := readChecksumIndex(c.path)
+	if err != nil {
+		return err
+	}
+	for path, key := range c.pruned {
+		if entry, ok := entries[path]; ok && entry.fileKey == key {
+			delete(entries, path) // unless saved again by another process for a newer version of the file
+		}
+	}
+	for path := range c.changed {
+		entry := c.entries[path]
+		if saved, ok := entries[path]; ok && saved.fileKey == entry.fileKey {
+			for algo, sum := range saved.Sums {
+				if _, ok := entry.Sums[algo]; !ok {
+					entry.Sums[algo] = sum
+				}
+			}
+		}
+		entries[path] = entry
+	}
+
+	data, err := json.Marshal(checksumCacheIndex{Version: checksumCacheVersion, Entries: entries})
+	if err != nil {
+		return fmt.Errorf("failed to encode checksum cache: %w", err)
+	}
+	if err := WriteFileAtomic(c.path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save checksum cache: %w", err)
+	}
+	c.entries = entries
+	c.changed, c.pruned = map[string]bool{}, map[string]fileKey{}
+	return nil
+}
+
+// ChecksumOption configures Checksum
+type ChecksumOption func(*checksumOptions)
+
+// checksumOptions holds the settings made by ChecksumOption
+type checksumOptions struct {
+	cache *ChecksumCache
+}
+
+// WithChecksumCache makes Checksum use the cache, see ChecksumCache.Checksum
+func WithChecksumCache(c *ChecksumCache) ChecksumOption {
+	return func(o *checksumOptions) { o.cache = c }
+}
@@ -0,0 +1,201 @@
+package fileutils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+func TestChecksumCache(t *testing.T) {
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	path := writeChecksumTestFile(t)
+	absPath, err := filepath.Abs(path)
+	require.NoError(t, err)
+
+	cache, err := NewChecksumCache(cacheDir)
+	require.NoError(t, err)
+	sum, err := cache.Checksum(path, enum.HashAlgSHA256)
+	require.NoError(t, err)
+	assert.Equal(t, checksumTestSHA256, sum)
+	sum, err = cache.Checksum(path, enum.HashAlgMD5)
+	require.NoError(t, err)
+	assert.Equal(t, checksumTestMD5, sum)
+	require.NoError(t, cache.Save())
+
+	// a fake sum tells a cached checksum from a calculated one
+	loaded, err := NewChecksumCache(cacheDir)
+	require.NoError(t, err)
+	require.Contains(t, loaded.entries, absPath)
+	assert.Equal(t, map[string]string{"SHA256": checksumTestSHA256, "MD5": checksumTestMD5}, loaded.entries[absPath].Sums)
+	loaded.entries[absPath].Sums["SHA256"] = "cached"
+	sum, err = loaded.Checksum(path, enum.HashAlgSHA256)
+	require.NoError(t, err)
+	assert.Equal(t, "cached", sum)
+
+	t.Run("invalidated by change", func(t *testing.T) {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		// same size and modification time, still detected on unix by the change time, elsewhere by the new size
+		content := []byte("THIS is a test file for checksum calculation")
+		if runtime.GOOS == "windows" {
+			content = append(content, '!')
+		}
+		require.NoError(t, os.WriteFile(path, content, 0o600))
+		require.NoError(t, os.Chtimes(path, info.ModTime(), info.ModTime()))
+
+		want, err := Checksum(path, enum.HashAlgSHA256)
+		require.NoError(t, err)
+		sum, err := loaded.Checksum(path, enum.HashAlgSHA256)
+		require.NoError(t, err)
+		assert.Equal(t, want, sum)
+		assert.Equal(t, map[string]string{"SHA256": want}, loaded.entries[absPath].Sums, "old sums dropped")
+	})
+
+	t.Run("prune", func(t *testing.T) {
+		other := filepath.Join(t.TempDir(), "other.txt")
+		require.NoError(t, os.WriteFile(other, []byte("other"), 0o600))
+		_, err := loaded.Checksum(other, enum.HashAlgSHA256)
+		require.NoError(t, err)
+		require.Len(t, loaded.entries, 2)
+
+		assert.Equal(t, 0, loaded.Prune())
+		require.NoError(t, os.Remove(other))
+		assert.Equal(t, 1, loaded.Prune())
+		assert.Len(t, loaded.entries, 1)
+
+		require.NoError(t, loaded.Save())
+		reloaded, err := NewChecksumCache(cacheDir)
+		require.NoError(t, err)
+		assert.Equal(t, loaded.entries, reloaded.entries)
+	})
+
+	t.Run("concurrent use", func(t *testing.T) {
+		cache, err := NewChecksumCache(t.TempDir())
+		require.NoError(t, err)
+		dir := t.TempDir()
+		writeTestTree(t, dir, map[string]string{"a.txt": "a", "b.txt": "b", "c.txt": "c"})
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+					_, err := cache.Checksum(filepath.Join(dir, name), enum.HashAlgSHA1)
+					assert.NoError(t, err)
+				}
+				assert.NoError(t, cache.Save())
+			}()
+		}
+		wg.Wait()
+		assert.Len(t, cache.entries, 3)
+	})
+
+	t.Run("shared directory", func(t *testing.T) {
+		dir, shared := t.TempDir(), t.TempDir()
+		writeTestTree(t, dir, map[string]string{"a.txt": "a", "b.txt": "b"})
+		first, err := NewChecksumCache(shared)
+		require.NoError(t, err)
+		second, err := NewChecksumCache(shared)
+		require.NoError(t, err)
+
+		_, err = first.Checksum(filepath.Join(dir, "a.txt"), enum.HashAlgSHA256)
+		require.NoError(t, err)
+		_, err = second.Checksum(filepath.Join(dir, "b.txt"), enum.HashAlgSHA256)
+		require.NoError(t, err)
+		_, err = second.Checksum(filepath.Join(dir, "a.txt"), enum.HashAlgMD5)
+		require.NoError(t, err)
+		require.NoError(t, first.Save())
+		require.NoError(t, second.Save())
+		assert.Len(t, second.entries, 2, "entries saved by the first cache picked up")
+
+		reloaded, err := NewChecksumCache(shared)
+		require.NoError(t, err)
+		require.Len(t, reloaded.entries, 2, "entries of both caches kept")
+		absA, err := filepath.Abs(filepath.Join(dir, "a.txt"))
+		require.NoError(t, err)
+		assert.Len(t, reloaded.entries[absA].Sums, 2, "sums of both caches merged")
+
+		// an entry pruned by one cache is dropped from the index
+		require.NoError(t, os.Remove(filepath.Join(dir, "b.txt")))
+		assert.Equal(t, 1, second.Prune())
+		require.NoError(t, second.Save())
+		reloaded, err = NewChecksumCache(shared)
+		require.NoError(t, err)
+		assert.Len(t, reloaded.entries, 1)
+	})
+
+	t.Run("corrupted index", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, checksumCacheFile), []byte("{not json"), 0o600))
+		cache, err := NewChecksumCache(dir)
+		require.NoError(t, err)
+		assert.Empty(t, cache.entries)
+		require.NoError(t, cache.Save(), "nothing to save")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := NewChecksumCache("")
+		require.Error(t, err)
+		_, err = NewChecksumCache(path)
+		require.Error(t, err)
+
+		_, err = cache.Checksum("", enum.HashAlgSHA256)
+		require.Error(t, err)
+		_, err = cache.Checksum(path, enum.HashAlg{})
+		require.Error(t, err)
+		_, err = cache.Checksum("nonexistent.txt", enum.HashAlgSHA256)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "file not found")
+	})
+}
+
+func TestChecksumDirWithCache(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{"checksum_test.txt": checksumTestContent, "sub/b.txt": "file b"})
+	cache, err := NewChecksumCache(t.TempDir())
+	require.NoError(t, err)
+
+	uncached, err := ChecksumDir(dir, enum.HashAlgSHA256, DirHashOptions{})
+	require.NoError(t, err)
+	cached, err := ChecksumDir(dir, enum.HashAlgSHA256, DirHashOptions{Cache: cache})
+	require.NoError(t, err)
+	assert.Equal(t, uncached, cached)
+	assert.Len(t, cache.entries, 2)
+
+	// a file changed since the cache was filled is read again
+	path := filepath.Join(dir, "sub", "b.txt")
+	require.NoError(t, os.WriteFile(path, []byte("changed b"), 0o600))
+	require.NoError(t, os.Chtimes(path, time.Now().Add(time.Hour), time.Now().Add(time.Hour)))
+	changed, err := ChecksumDir(dir, enum.HashAlgSHA256, DirHashOptions{Cache: cache})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sub/b.txt"}, DiffDirTrees(cached, changed))
+}
+
+func TestChecksumWithCache(t *testing.T) {
+	path := writeChecksumTestFile(t)
+	absPath, err := filepath.Abs(path)
+	require.NoError(t, err)
+	cache, err := NewChecksumCache(t.TempDir())
+	require.NoError(t, err)
+
+	sum, err := Checksum(path, enum.HashAlgSHA256, WithChecksumCache(cache))
+	require.NoError(t, err)
+	assert.Equal(t, checksumTestSHA256, sum)
+	require.Contains(t, cache.entries, absPath)
+
+	cache.entries[absPath].Sums["SHA256"] = "cached"
+	sum, err = Checksum(path, enum.HashAlgSHA256, WithChecksumCache(cache))
+	require.NoError(t, err)
+	assert.Equal(t, "cached", sum)
+	sum, err = Checksum(path, enum.HashAlgSHA256)
+	require.NoError(t, err)
+	assert.Equal(t, checksumTestSHA256, sum, "no cache without the option")
+}
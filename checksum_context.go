@@ -0,0 +1,156 @@
+package fileutils
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+// ChecksumResult is the checksum of a single file calculated by ChecksumFiles
+type ChecksumResult struct {
+	Path string // path as passed to ChecksumFiles
+	Sum  string // hex encoded checksum, empty if Err is set
+	Err  error  // error of this file, not stopping other files from being hashed
+}
+
+// ChecksumContext calculates a file checksum the way Checksum does, but stops when ctx is canceled,
+// checking it between read chunks, so even a huge file doesn't delay the cancellation.
+// The returned error wraps the context error in this case.
+func ChecksumContext(ctx context.Context, path string, algo enum.HashAlg) (string, error) {
+	if path == "" {
+		return "", errors.New("empty path")
+	}
+
+	h, err := newHash(algo)
+	if err != nil {
+		return "", err
+	}
+	return checksumFileContext(ctx, path, h)
+}
+
+// ChecksumContextByName is ChecksumContext with the hash algorithm given by name, see HashByName
+func ChecksumContextByName(ctx context.Context, path, name string) (string, error) {
+	if path == "" {
+		return "", errors.New("empty path")
+	}
+
+	h, err := HashByName(name)
+	if err != nil {
+		return "", err
+	}
+	return checksumFileContext(ctx, path, h)
+}
+
+// ChecksumFiles calculates checksums of many files in parallel using up to workers goroutines,
+// runtime.GOMAXPROCS if zero or negative. Returns a result for every path in the order of paths,
+// with errors reported per file. When ctx is canceled the hashing stops promptly, results of files
+// not finished get the context error, which is returned as well. An unsupported algorithm fails the call.
+func ChecksumFiles(ctx context.Context, paths []string, algo enum.HashAlg, workers int) ([]ChecksumResult, error) {
+	if _, err := newHash(algo); err != nil {
+		return nil, err
+	}
+	return checksumFiles(ctx, paths, func(path string) (string, error) { return ChecksumContext(ctx, path, algo) }, workers)
+}
+
+// ChecksumFilesByName is ChecksumFiles with the hash algorithm given by name, see HashByName
+func ChecksumFilesByName(ctx context.Context, paths []string, name string, workers int) ([]ChecksumResult, error) {
+	if _, err := HashByName(name); err != nil {
+		return nil, err
+	}
+	return checksumFiles(ctx, paths, func(path string) (string, error) { return ChecksumContextByName(ctx, path, name) }, workers)
+}
+
+// checksumFiles runs sum for every path in parallel, see ChecksumFiles
+func checksumFiles(ctx context.Context, paths []string, sum func(path string) (string, error), workers int) ([]ChecksumResult, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+
+	res := make([]ChecksumResult, len(paths))
+	for i, path := range paths {
+		res[i] = ChecksumResult{Path: path}
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs { // each worker writes only the results of its own jobs
+				res[idx].Sum, res[idx].Err = sum(res[idx].Path)
+			}
+		}()
+	}
+
+	next := 0
+feed:
+	for ; next < len(paths); next++ {
+		select {
+		case jobs <- next:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	err := ctx.Err()
+	if err == nil {
+		return res, nil
+	}
+	interrupted := next < len(paths)
+	for i := range res {
+		if i >= next {
+			res[i].Err = err
+		}
+		interrupted = interrupted || errors.Is(res[i].Err, err)
+	}
+	if !interrupted {
+		return res, nil // canceled after every file was hashed
+	}
+	return res, err
+}
+
+// checksumFileContext hashes the content of the file at path with h, returning the hex encoded checksum.
+// Stops with the context error when ctx is canceled.
+func checksumFileContext(ctx context.Context, path string, h hash.Hash) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	f, err := openChecksumFile(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err := io.Copy(h, &contextReader{ctx: ctx, r: f}); err != nil {
+		return "", fmt.Errorf("failed to read file %s for hashing: %w", path, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// contextReader is a reader failing with the context error once the context is canceled
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// Read reads from the underlying reader unless the context is canceled
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
@@ -0,0 +1,123 @@
+package fileutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+func TestChecksumContext(t *testing.T) {
+	path := writeChecksumTestFile(t)
+
+	sum, err := ChecksumContext(context.Background(), path, enum.HashAlgSHA256)
+	require.NoError(t, err)
+	assert.Equal(t, checksumTestSHA256, sum)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = ChecksumContext(ctx, path, enum.HashAlgSHA256)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	t.Run("canceled while reading", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var reads int
+		h, err := newHash(enum.HashAlgSHA256)
+		require.NoError(t, err)
+		r := &contextReader{ctx: ctx, r: readerFunc(func(p []byte) (int, error) {
+			if reads++; reads == 3 {
+				cancel()
+			}
+			return len(p), nil // endless stream
+		})}
+		_, err = io.Copy(h, r)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.Equal(t, 3, reads)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := ChecksumContext(context.Background(), "", enum.HashAlgSHA256)
+		require.Error(t, err)
+		_, err = ChecksumContext(context.Background(), path, enum.HashAlg{})
+		require.Error(t, err)
+		_, err = ChecksumContext(context.Background(), "nonexistent.txt", enum.HashAlgSHA256)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "file not found")
+	})
+}
+
+func TestChecksumFiles(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	want := map[string]string{}
+	for i := 0; i < 20; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("file%02d.txt", i))
+		require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf("content %d", i)), 0o600))
+		sum, err := Checksum(path, enum.HashAlgSHA1)
+		require.NoError(t, err)
+		paths, want[path] = append(paths, path), sum
+	}
+	paths = append(paths, filepath.Join(dir, "missing.txt"), paths[0])
+
+	for _, workers := range []int{0, 1, 4, 100} {
+		res, err := ChecksumFiles(context.Background(), paths, enum.HashAlgSHA1, workers)
+		require.NoError(t, err)
+		require.Len(t, res, len(paths))
+		for i, r := range res {
+			assert.Equal(t, paths[i], r.Path)
+			if i == 20 {
+				require.Error(t, r.Err)
+				assert.Contains(t, r.Err.Error(), "file not found")
+				continue
+			}
+			require.NoError(t, r.Err)
+			assert.Equal(t, want[r.Path], r.Sum, "workers %d, file %d", workers, i)
+		}
+	}
+
+	res, err := ChecksumFiles(context.Background(), nil, enum.HashAlgSHA1, 4)
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	_, err = ChecksumFiles(context.Background(), paths, enum.HashAlg{}, 4)
+	require.Error(t, err)
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		res, err := ChecksumFiles(ctx, paths, enum.HashAlgSHA1, 2)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, context.Canceled))
+		require.Len(t, res, len(paths))
+		for i, r := range res {
+			assert.Equal(t, paths[i], r.Path)
+			assert.Empty(t, r.Sum)
+			assert.True(t, errors.Is(r.Err, context.Canceled), "file %d: %v", i, r.Err)
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		<-ctx.Done()
+		_, err := ChecksumFiles(ctx, paths, enum.HashAlgSHA1, 2)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	})
+}
+
+// readerFunc makes a reader of a function
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) { return f(p) }
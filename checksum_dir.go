@@ -0,0 +1,189 @@
+package fileutils
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+// DirHashOptions controls ChecksumDir
+type DirHashOptions struct {
+	IncludeModes bool           // make permission bits part of the digest, not only names and content
+	Cache        *ChecksumCache // optional cache of file checksums, so unchanged files are not read again
+}
+
+// DirNode is a file, symlink or directory in the tree built by ChecksumDir
+type DirNode struct {
+	Path     string      // slash-separated path relative to the hashed directory, "." for the directory itself
+	Mode     os.FileMode // type bits, and permission bits with DirHashOptions.IncludeModes
+	Size     int64       // size of a file, zero for directories
+	Sum      string      // hex encoded digest of the node
+	Children []*DirNode  // entries of a directory, sorted by name
+}
+
+// IsDir checks if the node is a directory
+func (n *DirNode) IsDir() bool {
+	return n.Mode.IsDir()
+}
+
+// ChecksumDir calculates a digest of a whole directory tree, which changes if any name, file content or,
+// with opts.IncludeModes, permission bits change, and doesn't depend on walk order or OS path separator.
+// Returns the tree of nodes the digest is built from, with the root's Sum being the digest of dir,
+// so two trees can be compared with DiffDirTrees to locate changes.
+// The digest of a file is the checksum of its content, as Checksum calculates it, of a symlink the checksum
+// of its target path, and of a directory the checksum of its sorted entries, each made of the entry's type,
+// name, permission bits if included and digest, the way git builds tree objects.
+// Symlinks are not followed, and special files like devices, sockets and pipes are skipped.
+func ChecksumDir(dir string, algo enum.HashAlg, opts DirHashOptions) (*DirNode, error) {
+	if _, err := newHash(algo); err != nil {
+		return nil, err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("can't stat %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("not a directory: %s", dir)
+	}
+	return hashDirNode(dir, ".", info, algo, opts)
+}
+
+// hashDirNode builds the node for path, named rel relative to the hashed directory, returning nil for skipped files
+func hashDirNode(path, rel string, info os.FileInfo, algo enum.HashAlg, opts DirHashOptions) (*DirNode, error) {
+	node := &DirNode{Path: rel, Mode: info.Mode().Type()}
+	if opts.IncludeModes {
+		node.Mode = info.Mode()
+	}
+	h, _ := newHash(algo) // the algorithm is checked by ChecksumDir already
+
+	switch {
+	case info.Mode().IsRegular():
+		var sum string
+		var err error
+		if opts.Cache != nil {
+			sum, err = opts.Cache.Checksum(path, algo)
+		} else {
+			sum, err = checksumFile(path, h)
+		}
+		if err != nil {
+			return nil, err
+		}
+		node.Size, node.Sum = info.Size(), sum
+		return node, nil
+
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil, fmt.Errorf("can't read symlink %s: %w", path, err)
+		}
+		_, _ = h.Write([]byte(filepath.ToSlash(target)))
+		node.Sum = hex.EncodeToString(h.Sum(nil))
+		return node, nil
+
+	case info.IsDir():
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("can't read directory %s: %w", path, err)
+		}
+		for _, entry := range entries { // sorted by name already
+			childInfo, err := entry.Info()
+			if err != nil {
+				return nil, fmt.Errorf("can't stat %s: %w", filepath.Join(path, entry.Name()), err)
+			}
+			childRel := entry.Name()
+			if rel != "." {
+				childRel = rel + "/" + entry.Name()
+			}
+			child, err := hashDirNode(filepath.Join(path, entry.Name()), childRel, childInfo, algo, opts)
+			if err != nil {
+				return nil, err
+			}
+			if child == nil {
+				continue
+			}
+			node.Children = append(node.Children, child)
+			writeDirEntry(h, entry.Name(), child, opts)
+		}
+		node.Sum = hex.EncodeToString(h.Sum(nil))
+		return node, nil
+
+	default:
+		return nil, nil // devices, sockets and pipes have no content to hash
+	}
+}
+
+// writeDirEntry adds a directory entry to the digest of its directory: a type byte, the name terminated by NUL,
+// which can't be part of a name, the permission bits if included, and the raw digest of the entry
+func writeDirEntry(w io.Writer, name string, node *DirNode, opts DirHashOptions) {
+	entryType := byte('f')
+	switch {
+	case node.IsDir():
+		entryType = 'd'
+	case node.Mode&os.ModeSymlink != 0:
+		entryType = 'l'
+	}
+	_, _ = w.Write(append([]byte{entryType}, name...))
+	_, _ = w.Write([]byte{0})
+	if opts.IncludeModes { // permission bits go to the entry, as for git, so a file's digest stays its checksum
+		var mode [4]byte
+		binary.BigEndian.PutUint32(mode[:], uint32(node.Mode.Perm()))
+		_, _ = w.Write(mode[:])
+	}
+	sum, _ := hex.DecodeString(node.Sum)
+	_, _ = w.Write(sum)
+}
+
+// DiffDirTrees compares two trees built by ChecksumDir with the same algorithm and options, returning
+// the sorted paths of nodes that differ: added or removed entries and files, symlinks or directories
+// of different content, type or permission bits. Subtrees with equal digests are not descended into.
+// A changed file is reported by its own path only, not by the paths of the directories holding it.
+func DiffDirTrees(a, b *DirNode) []string {
+	var res []string
+	diffDirNodes(a, b, &res)
+	sort.Strings(res)
+	return res
+}
+
+// diffDirNodes adds the paths of differing nodes under a and b to res
+func diffDirNodes(a, b *DirNode, res *[]string) {
+	switch {
+	case a == nil && b == nil:
+		return
+	case a == nil:
+		*res = append(*res, b.Path)
+		return
+	case b == nil:
+		*res = append(*res, a.Path)
+		return
+	}
+
+	if a.Sum == b.Sum && a.Mode == b.Mode {
+		return
+	}
+	if !a.IsDir() || !b.IsDir() {
+		*res = append(*res, a.Path)
+		return
+	}
+	if a.Mode != b.Mode {
+		*res = append(*res, a.Path) // same entries, or not, the directory itself changed too
+	}
+
+	children := map[string][2]*DirNode{}
+	for _, c := range a.Children {
+		children[c.Path] = [2]*DirNode{c, nil}
+	}
+	for _, c := range b.Children {
+		pair := children[c.Path]
+		pair[1] = c
+		children[c.Path] = pair
+	}
+	for _, pair := range children {
+		diffDirNodes(pair[0], pair[1], res)
+	}
+}
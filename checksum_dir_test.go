@@ -0,0 +1,116 @@
+package fileutils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+func TestChecksumDir(t *testing.T) {
+	files := map[string]string{
+		"checksum_test.txt": checksumTestContent,
+		"sub/b.txt":         "file b",
+		"sub/deep/c.txt":    "file c",
+	}
+	dir := t.TempDir()
+	writeTestTree(t, dir, files)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "empty"), 0o750))
+
+	tree, err := ChecksumDir(dir, enum.HashAlgSHA256, DirHashOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, ".", tree.Path)
+	assert.True(t, tree.IsDir())
+	assert.Len(t, tree.Sum, 64)
+	require.Len(t, tree.Children, 3)
+	assert.Equal(t, "checksum_test.txt", tree.Children[0].Path)
+	assert.Equal(t, checksumTestSHA256, tree.Children[0].Sum, "file digest is its checksum")
+	assert.Equal(t, int64(len(checksumTestContent)), tree.Children[0].Size)
+	assert.Equal(t, "empty", tree.Children[1].Path)
+	assert.Equal(t, "sub/deep/c.txt", tree.Children[2].Children[1].Children[0].Path)
+
+	t.Run("stable for the same content", func(t *testing.T) {
+		other := t.TempDir()
+		writeTestTree(t, other, files)
+		require.NoError(t, os.Mkdir(filepath.Join(other, "empty"), 0o750))
+
+		otherTree, err := ChecksumDir(other, enum.HashAlgSHA256, DirHashOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, tree.Sum, otherTree.Sum)
+		assert.Empty(t, DiffDirTrees(tree, otherTree))
+	})
+
+	t.Run("changes", func(t *testing.T) {
+		other := t.TempDir()
+		writeTestTree(t, other, files)
+		writeTestTree(t, other, map[string]string{"sub/deep/c.txt": "changed", "new.txt": "added"})
+		require.NoError(t, os.Remove(filepath.Join(other, "sub", "b.txt")))
+
+		otherTree, err := ChecksumDir(other, enum.HashAlgSHA256, DirHashOptions{})
+		require.NoError(t, err)
+		assert.NotEqual(t, tree.Sum, otherTree.Sum)
+		assert.Equal(t, []string{"empty", "new.txt", "sub/b.txt", "sub/deep/c.txt"}, DiffDirTrees(tree, otherTree))
+	})
+
+	t.Run("renamed file", func(t *testing.T) {
+		other := t.TempDir()
+		writeTestTree(t, other, map[string]string{"checksum_test.txt": checksumTestContent})
+		otherTree, err := ChecksumDir(other, enum.HashAlgSHA256, DirHashOptions{})
+		require.NoError(t, err)
+
+		require.NoError(t, os.Rename(filepath.Join(other, "checksum_test.txt"), filepath.Join(other, "renamed.txt")))
+		renamedTree, err := ChecksumDir(other, enum.HashAlgSHA256, DirHashOptions{})
+		require.NoError(t, err)
+		assert.NotEqual(t, otherTree.Sum, renamedTree.Sum)
+		assert.Equal(t, []string{"checksum_test.txt", "renamed.txt"}, DiffDirTrees(otherTree, renamedTree))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := ChecksumDir(filepath.Join(dir, "missing"), enum.HashAlgSHA256, DirHashOptions{})
+		require.Error(t, err)
+		_, err = ChecksumDir(filepath.Join(dir, "checksum_test.txt"), enum.HashAlgSHA256, DirHashOptions{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not a directory")
+		_, err = ChecksumDir(dir, enum.HashAlg{}, DirHashOptions{})
+		require.Error(t, err)
+	})
+}
+
+func TestChecksumDirModesAndSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits and symlinks are not portable to windows")
+	}
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{"a.txt": "file a"})
+	require.NoError(t, os.Symlink("a.txt", filepath.Join(dir, "link")))
+
+	plain, err := ChecksumDir(dir, enum.HashAlgSHA256, DirHashOptions{})
+	require.NoError(t, err)
+	withModes, err := ChecksumDir(dir, enum.HashAlgSHA256, DirHashOptions{IncludeModes: true})
+	require.NoError(t, err)
+	assert.NotEqual(t, plain.Sum, withModes.Sum)
+	require.Len(t, plain.Children, 2)
+	assert.Equal(t, "link", plain.Children[1].Path)
+	assert.NotEqual(t, plain.Children[0].Sum, plain.Children[1].Sum, "symlink is hashed by target, not followed")
+
+	require.NoError(t, os.Chmod(filepath.Join(dir, "a.txt"), 0o644)) //nolint:gosec // test file
+	plainChmod, err := ChecksumDir(dir, enum.HashAlgSHA256, DirHashOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, plain.Sum, plainChmod.Sum, "modes are ignored by default")
+
+	modesChmod, err := ChecksumDir(dir, enum.HashAlgSHA256, DirHashOptions{IncludeModes: true})
+	require.NoError(t, err)
+	assert.NotEqual(t, withModes.Sum, modesChmod.Sum)
+	assert.Equal(t, []string{"a.txt"}, DiffDirTrees(withModes, modesChmod))
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "link")))
+	require.NoError(t, os.Symlink("other.txt", filepath.Join(dir, "link")))
+	retargeted, err := ChecksumDir(dir, enum.HashAlgSHA256, DirHashOptions{IncludeModes: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"link"}, DiffDirTrees(modesChmod, retargeted))
+}
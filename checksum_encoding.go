@@ -0,0 +1,164 @@
+package fileutils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+//go:generate enum -type=checksumEncoding -path=enum
+
+// checksumEncoding is the text encoding of a checksum
+//
+//nolint:unused // This type is used by the enum generator
+type checksumEncoding int
+
+// Checksum encodings
+//
+//nolint:unused // These constants are used by the enum generator
+const (
+	checksumEncodingHex checksumEncoding = iota + 1
+	checksumEncodingBase64
+	checksumEncodingBase64URL
+)
+
+// sriAlgos maps the hash algorithms allowed in Subresource Integrity strings to their SRI names,
+// in order of preference
+var sriAlgos = []struct {
+	algo enum.HashAlg
+	name string
+}{
+	{enum.HashAlgSHA512, "sha512"},
+	{enum.HashAlgSHA384, "sha384"},
+	{enum.HashAlgSHA256, "sha256"},
+}
+
+// ChecksumBytes is Checksum returning the raw checksum bytes
+func ChecksumBytes(path string, algo enum.HashAlg) ([]byte, error) {
+	sum, err := Checksum(path, algo)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(sum)
+}
+
+// EncodeChecksum encodes raw checksum bytes as lowercase hex, as Checksum returns it, standard base64,
+// or unpadded URL-safe base64. An unknown encoding is treated as hex.
+func EncodeChecksum(sum []byte, enc enum.ChecksumEncoding) string {
+	switch enc {
+	case enum.ChecksumEncodingBase64:
+		return base64.StdEncoding.EncodeToString(sum)
+	case enum.ChecksumEncodingBase64URL:
+		return base64.RawURLEncoding.EncodeToString(sum)
+	default:
+		return hex.EncodeToString(sum)
+	}
+}
+
+// ChecksumSRI calculates the checksum of a file as a Subresource Integrity string, like "sha384-<base64>",
+// ready for the integrity attribute of HTML script and link elements. SRI allows SHA256, SHA384 and SHA512 only.
+func ChecksumSRI(path string, algo enum.HashAlg) (string, error) {
+	if _, err := sriName(algo); err != nil {
+		return "", err
+	}
+	sum, err := ChecksumBytes(path, algo)
+	if err != nil {
+		return "", err
+	}
+	return FormatSRI(algo, sum)
+}
+
+// FormatSRI formats raw checksum bytes as a Subresource Integrity string
+func FormatSRI(algo enum.HashAlg, sum []byte) (string, error) {
+	name, err := sriName(algo)
+	if err != nil {
+		return "", err
+	}
+	return name + "-" + base64.StdEncoding.EncodeToString(sum), nil
+}
+
+// ParseSRI parses a Subresource Integrity string into the hash algorithm and the raw checksum.
+// An integrity value can list several space-separated checksums, the strongest one is returned, as browsers do,
+// and checksums of algorithms SRI doesn't know are skipped. Options following a "?" are ignored.
+func ParseSRI(s string) (enum.HashAlg, []byte, error) {
+	best := -1
+	var bestSum []byte
+	for _, token := range strings.Fields(s) {
+		token, _, _ = strings.Cut(token, "?")
+		name, value, _ := strings.Cut(token, "-")
+		for i, a := range sriAlgos {
+			if a.name != name {
+				continue
+			}
+			sum, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return enum.HashAlg{}, nil, fmt.Errorf("invalid integrity string %q: %w", token, err)
+			}
+			if best == -1 || i < best {
+				best, bestSum = i, sum
+			}
+		}
+	}
+	if best == -1 {
+		return enum.HashAlg{}, nil, fmt.Errorf("no supported checksum in integrity string %q", s)
+	}
+	return sriAlgos[best].algo, bestSum, nil
+}
+
+// DecodeChecksum decodes a checksum of the given algorithm in any of the encodings EncodeChecksum produces,
+// in either case for hex and with or without padding for base64, or as an SRI string of that algorithm.
+// The encoding is told apart by the length of the checksum the algorithm produces.
+func DecodeChecksum(s string, algo enum.HashAlg) ([]byte, error) {
+	h, err := newHash(algo)
+	if err != nil {
+		return nil, err
+	}
+	size := h.Size()
+	s = strings.TrimSpace(s)
+
+	if name, err := sriName(algo); err == nil && strings.HasPrefix(s, name+"-") {
+		if sriAlgo, sum, err := ParseSRI(s); err == nil && sriAlgo == algo && len(sum) == size {
+			return sum, nil
+		}
+	}
+
+	if len(s) == 2*size {
+		if sum, err := hex.DecodeString(s); err == nil {
+			return sum, nil
+		}
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if sum, err := enc.DecodeString(s); err == nil && len(sum) == size {
+			return sum, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid %s checksum %q", algo, s)
+}
+
+// ChecksumMatches checks if the checksum of a file equals the expected one, given in any encoding DecodeChecksum accepts
+func ChecksumMatches(path string, algo enum.HashAlg, expected string) (bool, error) {
+	want, err := DecodeChecksum(expected, algo)
+	if err != nil {
+		return false, err
+	}
+	got, err := ChecksumBytes(path, algo)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(got, want), nil
+}
+
+// sriName returns the SRI name of the hash algorithm
+func sriName(algo enum.HashAlg) (string, error) {
+	for _, a := range sriAlgos {
+		if a.algo == algo {
+			return a.name, nil
+		}
+	}
+	return "", errors.New("subresource integrity supports SHA256, SHA384 and SHA512 only, not " + algo.String())
+}
@@ -0,0 +1,136 @@
+package fileutils
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+const (
+	checksumTestSHA256Base64    = "dkS6eU1sTfMb1EDqn37Ly48vOEbMWPy/VdE1YOFoyGM="
+	checksumTestSHA256Base64URL = "dkS6eU1sTfMb1EDqn37Ly48vOEbMWPy_VdE1YOFoyGM"
+	checksumTestSHA384Base64    = "pN6D1lCopNB0g61hKWaF2dJh5u25QKAluLmB+QwXvcp5TUXyArK4w95c2cm89eHg"
+)
+
+func TestEncodeChecksum(t *testing.T) {
+	path := writeChecksumTestFile(t)
+	sum, err := ChecksumBytes(path, enum.HashAlgSHA256)
+	require.NoError(t, err)
+	assert.Len(t, sum, 32)
+
+	assert.Equal(t, checksumTestSHA256, EncodeChecksum(sum, enum.ChecksumEncodingHex))
+	assert.Equal(t, checksumTestSHA256Base64, EncodeChecksum(sum, enum.ChecksumEncodingBase64))
+	assert.Equal(t, checksumTestSHA256Base64URL, EncodeChecksum(sum, enum.ChecksumEncodingBase64URL))
+	assert.Equal(t, checksumTestSHA256, EncodeChecksum(sum, enum.ChecksumEncoding{}))
+
+	_, err = ChecksumBytes("nonexistent.txt", enum.HashAlgSHA256)
+	require.Error(t, err)
+}
+
+func TestChecksumSRI(t *testing.T) {
+	path := writeChecksumTestFile(t)
+
+	sri, err := ChecksumSRI(path, enum.HashAlgSHA384)
+	require.NoError(t, err)
+	assert.Equal(t, "sha384-"+checksumTestSHA384Base64, sri)
+
+	sri, err = ChecksumSRI(path, enum.HashAlgSHA256)
+	require.NoError(t, err)
+	assert.Equal(t, "sha256-"+checksumTestSHA256Base64, sri)
+
+	_, err = ChecksumSRI(path, enum.HashAlgMD5)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "subresource integrity supports")
+
+	_, err = ChecksumSRI("nonexistent.txt", enum.HashAlgSHA256)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "file not found")
+
+	_, err = FormatSRI(enum.HashAlgCRC32, []byte{1, 2, 3, 4})
+	require.Error(t, err)
+}
+
+func TestParseSRI(t *testing.T) {
+	sha256Sum, err := hex.DecodeString(checksumTestSHA256)
+	require.NoError(t, err)
+
+	algo, sum, err := ParseSRI("sha256-" + checksumTestSHA256Base64)
+	require.NoError(t, err)
+	assert.Equal(t, enum.HashAlgSHA256, algo)
+	assert.Equal(t, sha256Sum, sum)
+
+	// the strongest known checksum wins, unknown ones and options are skipped
+	algo, _, err = ParseSRI("md5-ZWsS/sNvffEXcbA8U+F3ug== sha256-" + checksumTestSHA256Base64 +
+		" sha384-" + checksumTestSHA384Base64 + "?ct=application/javascript")
+	require.NoError(t, err)
+	assert.Equal(t, enum.HashAlgSHA384, algo)
+
+	_, _, err = ParseSRI("md5-ZWsS/sNvffEXcbA8U+F3ug==")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no supported checksum")
+
+	_, _, err = ParseSRI("sha256-not*base64")
+	require.Error(t, err)
+
+	_, _, err = ParseSRI("")
+	require.Error(t, err)
+}
+
+func TestDecodeChecksum(t *testing.T) {
+	want, err := hex.DecodeString(checksumTestSHA256)
+	require.NoError(t, err)
+
+	for _, s := range []string{
+		checksumTestSHA256,
+		strings.ToUpper(checksumTestSHA256),
+		checksumTestSHA256Base64,
+		strings.TrimRight(checksumTestSHA256Base64, "="),
+		checksumTestSHA256Base64URL,
+		checksumTestSHA256Base64URL + "=",
+		"sha256-" + checksumTestSHA256Base64,
+		"  " + checksumTestSHA256 + "\n",
+	} {
+		sum, err := DecodeChecksum(s, enum.HashAlgSHA256)
+		require.NoError(t, err, s)
+		assert.Equal(t, want, sum, s)
+	}
+
+	for _, s := range []string{
+		"",
+		checksumTestSHA256[:62],
+		checksumTestMD5,
+		"sha384-" + checksumTestSHA384Base64,
+		"zz" + checksumTestSHA256[2:],
+	} {
+		_, err := DecodeChecksum(s, enum.HashAlgSHA256)
+		require.Error(t, err, s)
+	}
+
+	_, err = DecodeChecksum(checksumTestSHA256, enum.HashAlg{})
+	require.Error(t, err)
+}
+
+func TestChecksumMatches(t *testing.T) {
+	path := writeChecksumTestFile(t)
+
+	for _, expected := range []string{checksumTestSHA256, checksumTestSHA256Base64URL, "sha256-" + checksumTestSHA256Base64} {
+		ok, err := ChecksumMatches(path, enum.HashAlgSHA256, expected)
+		require.NoError(t, err)
+		assert.True(t, ok, expected)
+	}
+
+	ok, err := ChecksumMatches(path, enum.HashAlgSHA256, strings.Repeat("0", 64))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = ChecksumMatches(path, enum.HashAlgSHA256, "bad")
+	require.Error(t, err)
+
+	_, err = ChecksumMatches("nonexistent.txt", enum.HashAlgSHA256, checksumTestSHA256)
+	require.Error(t, err)
+}
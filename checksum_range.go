@@ -0,0 +1,117 @@
+package fileutils
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+// DefaultFingerprintBlock is the size of blocks ChecksumFingerprint samples if no block size is given
+const DefaultFingerprintBlock = 64 * 1024
+
+// ChecksumRange calculates the checksum of length bytes of a file starting at offset, e.g. to verify
+// a part of a resumed transfer. A negative length means the rest of the file.
+// Returns an error if the range goes beyond the end of the file.
+func ChecksumRange(path string, algo enum.HashAlg, offset, length int64) (string, error) {
+	if path == "" {
+		return "", errors.New("empty path")
+	}
+	if offset < 0 {
+		return "", fmt.Errorf("invalid offset %d", offset)
+	}
+
+	h, err := newHash(algo)
+	if err != nil {
+		return "", err
+	}
+	f, size, err := openChecksumRangeFile(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	if length < 0 {
+		length = size - offset
+	}
+	if offset > size || length > size-offset {
+		return "", fmt.Errorf("range %d+%d is beyond the end of file %s of size %d", offset, length, path, size)
+	}
+	if err := hashSection(h, f, offset, length); err != nil {
+		return "", fmt.Errorf("failed to read file %s for hashing: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ChecksumFingerprint calculates a fast fingerprint of a file from its size and the head, middle and tail blocks
+// of blockSize bytes, DefaultFingerprintBlock if zero or negative, so it reads at most three blocks of a huge file.
+// Files not larger than three blocks are hashed completely. Changes outside of the sampled blocks which
+// don't change the size are not detected, so the fingerprint is suitable for quick change detection,
+// but not for verifying content. The result is not comparable to Checksum of the same file.
+func ChecksumFingerprint(path string, algo enum.HashAlg, blockSize int64) (string, error) {
+	if path == "" {
+		return "", errors.New("empty path")
+	}
+	if blockSize <= 0 {
+		blockSize = DefaultFingerprintBlock
+	}
+
+	h, err := newHash(algo)
+	if err != nil {
+		return "", err
+	}
+	f, size, err := openChecksumRangeFile(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	var sizeBuf [8]byte
+	binary.BigEndian.PutUint64(sizeBuf[:], uint64(size))
+	_, _ = h.Write(sizeBuf[:])
+
+	offsets := []int64{0}
+	if size > 3*blockSize {
+		offsets = []int64{0, (size - blockSize) / 2, size - blockSize}
+	} else {
+		blockSize = size
+	}
+	for _, offset := range offsets {
+		if err := hashSection(h, f, offset, blockSize); err != nil {
+			return "", fmt.Errorf("failed to read file %s for hashing: %w", path, err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashSection writes length bytes of r starting at offset to h, failing if fewer are read,
+// e.g. because the file was truncated after its size was checked
+func hashSection(h io.Writer, r io.ReaderAt, offset, length int64) error {
+	n, err := io.CopyN(h, io.NewSectionReader(r, offset, length), length)
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("short read, %d of %d bytes: %w", n, length, io.ErrUnexpectedEOF)
+	}
+	return err
+}
+
+// openChecksumRangeFile opens a regular file for hashing, returning its size
+func openChecksumRangeFile(path string) (*os.File, int64, error) {
+	f, err := openChecksumFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, 0, fmt.Errorf("can't stat %s: %w", path, err)
+	}
+	if !info.Mode().IsRegular() {
+		_ = f.Close()
+		return nil, 0, fmt.Errorf("not a regular file: %s", path)
+	}
+	return f, info.Size(), nil
+}
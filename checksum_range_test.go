@@ -0,0 +1,133 @@
+package fileutils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+func TestChecksumRange(t *testing.T) {
+	path := writeChecksumTestFile(t)
+	size := int64(len(checksumTestContent))
+
+	sum, err := ChecksumRange(path, enum.HashAlgSHA256, 0, -1)
+	require.NoError(t, err)
+	assert.Equal(t, checksumTestSHA256, sum)
+
+	sum, err = ChecksumRange(path, enum.HashAlgSHA256, 0, size)
+	require.NoError(t, err)
+	assert.Equal(t, checksumTestSHA256, sum)
+
+	tbl := []struct {
+		offset, length int64
+		want           string
+	}{
+		{5, 7, checksumTestContent[5:12]},
+		{10, -1, checksumTestContent[10:]},
+		{size, 0, ""},
+		{size, -1, ""},
+	}
+	for _, tt := range tbl {
+		want, err := ChecksumReader(strings.NewReader(tt.want), enum.HashAlgMD5)
+		require.NoError(t, err)
+		sum, err := ChecksumRange(path, enum.HashAlgMD5, tt.offset, tt.length)
+		require.NoError(t, err, "%d+%d", tt.offset, tt.length)
+		assert.Equal(t, want, sum, "%d+%d", tt.offset, tt.length)
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		tbl := []struct {
+			path           string
+			algo           enum.HashAlg
+			offset, length int64
+			wantErr        string
+		}{
+			{"", enum.HashAlgSHA256, 0, -1, "empty path"},
+			{path, enum.HashAlgSHA256, -1, 1, "invalid offset"},
+			{path, enum.HashAlgSHA256, 40, 5, "beyond the end of file"},
+			{path, enum.HashAlgSHA256, size + 1, -1, "beyond the end of file"},
+			{path, enum.HashAlg{}, 0, 1, "unsupported hash algorithm"},
+			{"nonexistent.txt", enum.HashAlgSHA256, 0, 1, "file not found"},
+			{filepath.Dir(path), enum.HashAlgSHA256, 0, 1, "not a regular file"},
+		}
+		for _, tt := range tbl {
+			_, err := ChecksumRange(tt.path, tt.algo, tt.offset, tt.length)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		}
+	})
+}
+
+func TestChecksumFingerprint(t *testing.T) {
+	t.Run("small file is hashed completely", func(t *testing.T) {
+		path := writeChecksumTestFile(t)
+		var data bytes.Buffer
+		_ = binary.Write(&data, binary.BigEndian, uint64(len(checksumTestContent)))
+		data.WriteString(checksumTestContent)
+		want, err := ChecksumReader(&data, enum.HashAlgSHA256)
+		require.NoError(t, err)
+
+		sum, err := ChecksumFingerprint(path, enum.HashAlgSHA256, 0)
+		require.NoError(t, err)
+		assert.Equal(t, want, sum)
+
+		sum, err = ChecksumFingerprint(path, enum.HashAlgSHA256, 15)
+		require.NoError(t, err)
+		assert.Equal(t, want, sum, "44 bytes fit into three blocks of 15")
+	})
+
+	t.Run("large file is sampled", func(t *testing.T) {
+		const blockSize = 16
+		path := filepath.Join(t.TempDir(), "large.bin")
+		content := []byte(strings.Repeat("0123456789", 10)) // 100 bytes, blocks at 0, 42 and 84
+		require.NoError(t, os.WriteFile(path, content, 0o600))
+		orig, err := ChecksumFingerprint(path, enum.HashAlgSHA256, blockSize)
+		require.NoError(t, err)
+
+		fingerprint := func(change func(b []byte) []byte) string {
+			changed := change(append([]byte(nil), content...))
+			require.NoError(t, os.WriteFile(path, changed, 0o600))
+			sum, err := ChecksumFingerprint(path, enum.HashAlgSHA256, blockSize)
+			require.NoError(t, err)
+			return sum
+		}
+
+		assert.Equal(t, orig, fingerprint(func(b []byte) []byte { b[20] = 'x'; return b }), "not sampled")
+		assert.NotEqual(t, orig, fingerprint(func(b []byte) []byte { b[0] = 'x'; return b }), "head")
+		assert.NotEqual(t, orig, fingerprint(func(b []byte) []byte { b[50] = 'x'; return b }), "middle")
+		assert.NotEqual(t, orig, fingerprint(func(b []byte) []byte { b[99] = 'x'; return b }), "tail")
+		assert.NotEqual(t, orig, fingerprint(func(b []byte) []byte { return append(b[:84], b[85:]...) }), "size")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := ChecksumFingerprint("", enum.HashAlgSHA256, 0)
+		require.Error(t, err)
+		_, err = ChecksumFingerprint("nonexistent.txt", enum.HashAlgSHA256, 0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "file not found")
+		_, err = ChecksumFingerprint(writeChecksumTestFile(t), enum.HashAlg{}, 0)
+		require.Error(t, err)
+	})
+}
+
+func TestHashSection(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, hashSection(&buf, strings.NewReader("0123456789"), 2, 5))
+	assert.Equal(t, "23456", buf.String())
+
+	// fewer bytes than the size checked before, as for a file truncated meanwhile
+	buf.Reset()
+	err := hashSection(&buf, strings.NewReader("0123"), 2, 5)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	assert.Contains(t, err.Error(), "short read, 2 of 5 bytes")
+}
@@ -0,0 +1,246 @@
+package fileutils
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+// checksumTestContent is the content TestChecksum uses as well, with checksums known from there
+const (
+	checksumTestContent = "this is a test file for checksum calculation"
+	checksumTestMD5     = "656b12fec36f7df11771b03c53e177ba"
+	checksumTestSHA256  = "7644ba794d6c4df31bd440ea9f7ecbcb8f2f3846cc58fcbf55d13560e168c863"
+)
+
+// writeChecksumTestFile creates a file with checksumTestContent
+func writeChecksumTestFile(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "checksum_test.txt")
+	require.NoError(t, os.WriteFile(path, []byte(checksumTestContent), 0o600))
+	return path
+}
+
+func TestChecksumReader(t *testing.T) {
+	sum, err := ChecksumReader(strings.NewReader(checksumTestContent), enum.HashAlgSHA256)
+	require.NoError(t, err)
+	assert.Equal(t, checksumTestSHA256, sum)
+
+	sum, err = ChecksumReader(strings.NewReader(checksumTestContent), enum.HashAlgMD5)
+	require.NoError(t, err)
+	assert.Equal(t, checksumTestMD5, sum)
+
+	_, err = ChecksumReader(strings.NewReader(checksumTestContent), enum.HashAlg{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported hash algorithm")
+
+	_, err = ChecksumReader(io.MultiReader(strings.NewReader("data"), failingReader{}), enum.HashAlgSHA256)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "read failed")
+}
+
+func TestMultiChecksum(t *testing.T) {
+	path := writeChecksumTestFile(t)
+
+	sums, err := MultiChecksum(path, enum.HashAlgMD5, enum.HashAlgSHA256, enum.HashAlgMD5)
+	require.NoError(t, err)
+	assert.Equal(t, map[enum.HashAlg]string{
+		enum.HashAlgMD5:    checksumTestMD5,
+		enum.HashAlgSHA256: checksumTestSHA256,
+	}, sums)
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := MultiChecksum(path)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no hash algorithm")
+
+		_, err = MultiChecksum(path, enum.HashAlgSHA256, enum.HashAlg{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported hash algorithm")
+
+		_, err = MultiChecksum("", enum.HashAlgSHA256)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "empty path")
+
+		_, err = MultiChecksum("nonexistent.txt", enum.HashAlgSHA256)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "file not found")
+	})
+}
+
+func TestChecksumWriter(t *testing.T) {
+	var dst bytes.Buffer
+	cw, err := NewChecksumWriter(&dst, enum.HashAlgMD5, enum.HashAlgSHA256)
+	require.NoError(t, err)
+
+	n, err := io.Copy(cw, strings.NewReader(checksumTestContent))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(checksumTestContent)), n)
+	assert.Equal(t, checksumTestContent, dst.String())
+	assert.Equal(t, map[enum.HashAlg]string{
+		enum.HashAlgMD5:    checksumTestMD5,
+		enum.HashAlgSHA256: checksumTestSHA256,
+	}, cw.Sums())
+
+	t.Run("short write", func(t *testing.T) {
+		cw, err := NewChecksumWriter(&limitedWriter{limit: 4}, enum.HashAlgSHA256)
+		require.NoError(t, err)
+		n, err := cw.Write([]byte("data and more"))
+		require.Error(t, err)
+		assert.Equal(t, 4, n)
+
+		// only what reached the destination is hashed
+		sum, err := ChecksumReader(strings.NewReader("data"), enum.HashAlgSHA256)
+		require.NoError(t, err)
+		assert.Equal(t, sum, cw.Sums()[enum.HashAlgSHA256])
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := NewChecksumWriter(nil, enum.HashAlgSHA256)
+		require.Error(t, err)
+		_, err = NewChecksumWriter(io.Discard)
+		require.Error(t, err)
+		_, err = NewChecksumWriter(io.Discard, enum.HashAlg{})
+		require.Error(t, err)
+	})
+}
+
+// failingReader fails every read
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("read failed") }
+
+// limitedWriter accepts up to limit bytes and fails after that
+type limitedWriter struct {
+	limit   int
+	written int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.written+len(p) <= w.limit {
+		w.written += len(p)
+		return len(p), nil
+	}
+	n := w.limit - w.written
+	w.written = w.limit
+	return n, errors.New("write limit reached")
+}
+
+func TestRegisterHash(t *testing.T) {
+	path := writeChecksumTestFile(t)
+
+	// FNV-1, unlike FNV-1a, is not built in
+	require.NoError(t, RegisterHash("test-FNV32", func() hash.Hash { return fnv.New32() }))
+	t.Cleanup(func() { // the registry is global, keep repeated runs independent
+		hashRegistry.Lock()
+		delete(hashRegistry.hashes, "test-fnv32")
+		hashRegistry.Unlock()
+	})
+
+	sum, err := ChecksumByName(path, "test-fnv32")
+	require.NoError(t, err)
+	var h hash.Hash = fnv.New32()
+	_, _ = h.Write([]byte(checksumTestContent))
+	assert.Equal(t, hex.EncodeToString(h.Sum(nil)), sum)
+
+	// built-in algorithms are available by their enum names
+	sum, err = ChecksumByName(path, "sha256")
+	require.NoError(t, err)
+	assert.Equal(t, checksumTestSHA256, sum)
+
+	h, err = HashByName("TEST-fnv32")
+	require.NoError(t, err)
+	assert.Equal(t, 4, h.Size())
+
+	fnvSum, err := ChecksumByName(path, "test-fnv32")
+	require.NoError(t, err)
+
+	t.Run("entry points by name", func(t *testing.T) {
+		sums, err := MultiChecksumByName(path, "test-fnv32", "sha256")
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"test-fnv32": fnvSum, "sha256": checksumTestSHA256}, sums)
+
+		var dst bytes.Buffer
+		cw, err := NewChecksumWriterByName(&dst, "test-fnv32", "SHA256")
+		require.NoError(t, err)
+		_, err = cw.Write([]byte(checksumTestContent))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"test-fnv32": fnvSum, "SHA256": checksumTestSHA256}, cw.SumsByName())
+		assert.Equal(t, map[enum.HashAlg]string{enum.HashAlgSHA256: checksumTestSHA256}, cw.Sums(), "built-in algorithms only")
+
+		sum, err := ChecksumContextByName(context.Background(), path, "test-fnv32")
+		require.NoError(t, err)
+		assert.Equal(t, fnvSum, sum)
+
+		res, err := ChecksumFilesByName(context.Background(), []string{path, path}, "test-fnv32", 2)
+		require.NoError(t, err)
+		assert.Equal(t, []ChecksumResult{{Path: path, Sum: fnvSum}, {Path: path, Sum: fnvSum}}, res)
+
+		dstPath := filepath.Join(t.TempDir(), "copy.txt")
+		sum, err = CopyFileVerified(path, dstPath, "test-fnv32")
+		require.NoError(t, err)
+		assert.Equal(t, fnvSum, sum)
+		content, err := os.ReadFile(dstPath) //nolint:gosec
+		require.NoError(t, err)
+		assert.Equal(t, checksumTestContent, string(content))
+
+		for _, err := range []error{
+			func() error { _, err := MultiChecksumByName(path, "test-unknown"); return err }(),
+			func() error { _, err := NewChecksumWriterByName(&dst, "test-unknown"); return err }(),
+			func() error { _, err := ChecksumContextByName(context.Background(), path, "test-unknown"); return err }(),
+			func() error {
+				_, err := ChecksumFilesByName(context.Background(), []string{path}, "test-unknown", 1)
+				return err
+			}(),
+			func() error { _, err := CopyFileVerified(path, dstPath, "test-unknown"); return err }(),
+		} {
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "unsupported hash algorithm")
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		newFNV := func() hash.Hash { return fnv.New32() }
+		tbl := []struct {
+			name    string
+			fn      func() hash.Hash
+			wantErr string
+		}{
+			{"", newFNV, "empty hash algorithm name"},
+			{"with space", newFNV, "invalid hash algorithm name"},
+			{"test-nil", nil, "hash constructor is required"},
+			{"SHA256", newFNV, "is built in"},
+			{"test-fnv32", newFNV, "already registered"},
+		}
+		for _, tt := range tbl {
+			err := RegisterHash(tt.name, tt.fn)
+			require.Error(t, err, tt.name)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		}
+
+		_, err := ChecksumByName(path, "test-unknown")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported hash algorithm")
+
+		_, err = ChecksumByName("", "sha256")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "empty path")
+
+		_, err = ChecksumByName("nonexistent.txt", "test-fnv32")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "file not found")
+	})
+}
@@ -0,0 +1,221 @@
+package fileutils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+//go:generate enum -type=diffReason -path=enum
+
+// diffReason is why CompareDirs considers two files different
+//
+//nolint:unused // This type is used by the enum generator
+type diffReason int
+
+// Diff reasons
+//
+//nolint:unused // These constants are used by the enum generator
+const (
+	diffReasonType    diffReason = iota + 1 // one is a symlink, the other is not
+	diffReasonSize                          // sizes differ
+	diffReasonModTime                       // modification times differ
+	diffReasonMode                          // permission bits differ
+	diffReasonContent                       // checksums or symlink targets differ
+)
+
+// CompareOptions controls CompareDirs
+type CompareOptions struct {
+	ModTime bool         // report files with different modification times
+	Mode    bool         // report files with different permission bits
+	Content bool         // compare checksums of files of the same size, reading both
+	Algo    enum.HashAlg // checksum algorithm for Content, SHA256 if not set
+}
+
+// DirDiff is the result of CompareDirs, all paths are slash-separated and relative to the compared directories
+type DirDiff struct {
+	OnlyA  []string   `json:"only_a"` // files found in the first directory only
+	OnlyB  []string   `json:"only_b"` // files found in the second directory only
+	Differ []FileDiff `json:"differ"` // files found in both, but different
+}
+
+// FileDiff is a file found in both compared directories, with the reasons to consider the two different
+type FileDiff struct {
+	Path    string            `json:"path"`
+	Reasons []enum.DiffReason `json:"reasons"`
+}
+
+// CompareDirs compares the files in directories a and b, as listed by ListFiles, reporting files found
+// in one of them only and files found in both, but of different size or, depending on opts, modification time,
+// permission bits or content. Symlinks are compared, not followed, with the target as their content.
+// Contents are compared only for files of the same size. All lists are sorted by path.
+func CompareDirs(a, b string, opts CompareOptions) (*DirDiff, error) {
+	if opts.Algo == (enum.HashAlg{}) {
+		opts.Algo = enum.HashAlgSHA256
+	}
+	if _, err := newHash(opts.Algo); err != nil {
+		return nil, err
+	}
+
+	filesA, err := listRelFiles(a)
+	if err != nil {
+		return nil, err
+	}
+	filesB, err := listRelFiles(b)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &DirDiff{}
+	inB := make(map[string]bool, len(filesB))
+	for _, rel := range filesB {
+		inB[rel] = true
+	}
+	inA := make(map[string]bool, len(filesA))
+	for _, rel := range filesA {
+		inA[rel] = true
+		if !inB[rel] {
+			res.OnlyA = append(res.OnlyA, rel)
+			continue
+		}
+		reasons, err := compareFiles(filepath.Join(a, filepath.FromSlash(rel)), filepath.Join(b, filepath.FromSlash(rel)), opts)
+		if err != nil {
+			return nil, err
+		}
+		if len(reasons) > 0 {
+			res.Differ = append(res.Differ, FileDiff{Path: rel, Reasons: reasons})
+		}
+	}
+	for _, rel := range filesB {
+		if !inA[rel] {
+			res.OnlyB = append(res.OnlyB, rel)
+		}
+	}
+	return res, nil
+}
+
+// Equal checks if the compared directories have the same files with no differences
+func (d *DirDiff) Equal() bool {
+	return len(d.OnlyA) == 0 && len(d.OnlyB) == 0 && len(d.Differ) == 0
+}
+
+// String renders the diff as text, a line per file sorted by path, with "-" marking files found in the first
+// directory only, "+" files found in the second one only, and "~" different files followed by the reasons
+func (d *DirDiff) String() string {
+	type line struct {
+		path, text string
+	}
+	lines := make([]line, 0, len(d.OnlyA)+len(d.OnlyB)+len(d.Differ))
+	for _, p := range d.OnlyA {
+		lines = append(lines, line{path: p, text: "- " + p})
+	}
+	for _, p := range d.OnlyB {
+		lines = append(lines, line{path: p, text: "+ " + p})
+	}
+	for _, f := range d.Differ {
+		reasons := make([]string, len(f.Reasons))
+		for i, r := range f.Reasons {
+			reasons[i] = r.String()
+		}
+		lines = append(lines, line{path: f.Path, text: fmt.Sprintf("~ %s (%s)", f.Path, strings.Join(reasons, ", "))})
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].path < lines[j].path })
+
+	var sb strings.Builder
+	for _, l := range lines {
+		sb.WriteString(l.text)
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// listRelFiles lists files in dir with ListFiles, returning sorted slash-separated paths relative to dir
+func listRelFiles(dir string) ([]string, error) {
+	if !IsDir(dir) {
+		return nil, fmt.Errorf("not a directory: %s", dir)
+	}
+	list, err := ListFiles(dir)
+	if err != nil {
+		return nil, fmt.Errorf("can't list files in %s: %w", dir, err)
+	}
+	res := make([]string, 0, len(list))
+	for _, file := range list {
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return nil, fmt.Errorf("can't get relative path of %s: %w", file, err)
+		}
+		res = append(res, filepath.ToSlash(rel))
+	}
+	sort.Strings(res) // ListFiles sorts by OS path, which may differ from the slash-separated order
+	return res, nil
+}
+
+// compareFiles returns the reasons to consider files a and b different, none if they are the same
+func compareFiles(a, b string, opts CompareOptions) ([]enum.DiffReason, error) {
+	infoA, err := os.Lstat(a)
+	if err != nil {
+		return nil, fmt.Errorf("can't stat %s: %w", a, err)
+	}
+	infoB, err := os.Lstat(b)
+	if err != nil {
+		return nil, fmt.Errorf("can't stat %s: %w", b, err)
+	}
+
+	var res []enum.DiffReason
+	if infoA.Mode().Type() != infoB.Mode().Type() {
+		return append(res, enum.DiffReasonType), nil // nothing else is comparable
+	}
+	if infoA.Size() != infoB.Size() {
+		res = append(res, enum.DiffReasonSize)
+	}
+	if opts.ModTime && !infoA.ModTime().Equal(infoB.ModTime()) {
+		res = append(res, enum.DiffReasonModTime)
+	}
+	if opts.Mode && infoA.Mode().Perm() != infoB.Mode().Perm() {
+		res = append(res, enum.DiffReasonMode)
+	}
+	if !opts.Content || infoA.Size() != infoB.Size() {
+		return res, nil
+	}
+
+	same, err := sameContent(a, b, infoA, opts.Algo)
+	if err != nil {
+		return nil, err
+	}
+	if !same {
+		res = append(res, enum.DiffReasonContent)
+	}
+	return res, nil
+}
+
+// sameContent checks if files a and b of the same type have the same checksum or, for symlinks, target
+func sameContent(a, b string, info os.FileInfo, algo enum.HashAlg) (bool, error) {
+	if info.Mode()&os.ModeSymlink != 0 {
+		targetA, err := os.Readlink(a)
+		if err != nil {
+			return false, fmt.Errorf("can't read symlink %s: %w", a, err)
+		}
+		targetB, err := os.Readlink(b)
+		if err != nil {
+			return false, fmt.Errorf("can't read symlink %s: %w", b, err)
+		}
+		return targetA == targetB, nil
+	}
+	if !info.Mode().IsRegular() {
+		return true, nil // devices, sockets and pipes have no content to compare
+	}
+
+	sumA, err := Checksum(a, algo)
+	if err != nil {
+		return false, err
+	}
+	sumB, err := Checksum(b, algo)
+	if err != nil {
+		return false, err
+	}
+	return sumA == sumB, nil
+}
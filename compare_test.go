@@ -0,0 +1,111 @@
+package fileutils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+func TestCompareDirs(t *testing.T) {
+	a, b := t.TempDir(), t.TempDir()
+	common := map[string]string{"same.txt": "same", "sub/same.txt": "same", "content.txt": "aaaa", "size.txt": "short"}
+	writeTestTree(t, a, common)
+	writeTestTree(t, b, common)
+	writeTestTree(t, a, map[string]string{"only-a.txt": "a", "sub/only-a.txt": "a"})
+	writeTestTree(t, b, map[string]string{"only-b.txt": "b", "content.txt": "bbbb", "size.txt": "longer"})
+
+	// the same modification times everywhere except of mtime.txt
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, dir := range []string{a, b} {
+		writeTestTree(t, dir, map[string]string{"mtime.txt": "mtime"})
+		for name := range common {
+			require.NoError(t, os.Chtimes(filepath.Join(dir, filepath.FromSlash(name)), ts, ts))
+		}
+		require.NoError(t, os.Chtimes(filepath.Join(dir, "mtime.txt"), ts, ts))
+	}
+	require.NoError(t, os.Chtimes(filepath.Join(b, "mtime.txt"), ts, ts.Add(time.Hour)))
+
+	t.Run("size only", func(t *testing.T) {
+		diff, err := CompareDirs(a, b, CompareOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, &DirDiff{
+			OnlyA:  []string{"only-a.txt", "sub/only-a.txt"},
+			OnlyB:  []string{"only-b.txt"},
+			Differ: []FileDiff{{Path: "size.txt", Reasons: []enum.DiffReason{enum.DiffReasonSize}}},
+		}, diff)
+		assert.False(t, diff.Equal())
+	})
+
+	t.Run("content and mtime", func(t *testing.T) {
+		diff, err := CompareDirs(a, b, CompareOptions{Content: true, ModTime: true, Algo: enum.HashAlgMD5})
+		require.NoError(t, err)
+		assert.Equal(t, []FileDiff{
+			{Path: "content.txt", Reasons: []enum.DiffReason{enum.DiffReasonContent}},
+			{Path: "mtime.txt", Reasons: []enum.DiffReason{enum.DiffReasonModTime}},
+			{Path: "size.txt", Reasons: []enum.DiffReason{enum.DiffReasonSize}},
+		}, diff.Differ)
+
+		assert.Equal(t, "~ content.txt (Content)\n"+
+			"~ mtime.txt (ModTime)\n"+
+			"- only-a.txt\n"+
+			"+ only-b.txt\n"+
+			"~ size.txt (Size)\n"+
+			"- sub/only-a.txt\n", diff.String())
+
+		data, err := json.Marshal(diff)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"only_a":["only-a.txt","sub/only-a.txt"],"only_b":["only-b.txt"],"differ":[`+
+			`{"path":"content.txt","reasons":["Content"]},{"path":"mtime.txt","reasons":["ModTime"]},`+
+			`{"path":"size.txt","reasons":["Size"]}]}`, string(data))
+
+		var decoded DirDiff
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, diff, &decoded)
+	})
+
+	t.Run("same directory", func(t *testing.T) {
+		diff, err := CompareDirs(a, a, CompareOptions{Content: true, ModTime: true, Mode: true})
+		require.NoError(t, err)
+		assert.True(t, diff.Equal())
+		assert.Empty(t, diff.String())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := CompareDirs(filepath.Join(a, "missing"), b, CompareOptions{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not a directory")
+		_, err = CompareDirs(a, filepath.Join(b, "same.txt"), CompareOptions{})
+		require.Error(t, err)
+	})
+}
+
+func TestCompareDirsModesAndSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits and symlinks are not portable to windows")
+	}
+	a, b := t.TempDir(), t.TempDir()
+	for _, dir := range []string{a, b} {
+		writeTestTree(t, dir, map[string]string{"mode.txt": "mode", "target.txt": "target", "kind": "file"})
+	}
+	require.NoError(t, os.Chmod(filepath.Join(b, "mode.txt"), 0o640))
+	require.NoError(t, os.Symlink("target.txt", filepath.Join(a, "link")))
+	require.NoError(t, os.Symlink("other0.txt", filepath.Join(b, "link")))
+	require.NoError(t, os.Remove(filepath.Join(b, "kind")))
+	require.NoError(t, os.Symlink("mode.txt", filepath.Join(b, "kind")))
+
+	diff, err := CompareDirs(a, b, CompareOptions{Mode: true, Content: true})
+	require.NoError(t, err)
+	assert.Equal(t, []FileDiff{
+		{Path: "kind", Reasons: []enum.DiffReason{enum.DiffReasonType}},
+		{Path: "link", Reasons: []enum.DiffReason{enum.DiffReasonContent}},
+		{Path: "mode.txt", Reasons: []enum.DiffReason{enum.DiffReasonMode}},
+	}, diff.Differ)
+}
@@ -0,0 +1,292 @@
+package fileutils
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+//go:generate enum -type=dedupAction -path=enum
+
+// dedupAction is what FindDuplicates does with duplicate files
+//
+//nolint:unused // This type is used by the enum generator
+type dedupAction int
+
+// Dedup actions
+//
+//nolint:unused // These constants are used by the enum generator
+const (
+	dedupActionNone     dedupAction = iota + 1 // report duplicates only
+	dedupActionHardlink                        // replace duplicates with hard links to the kept file
+	dedupActionReflink                         // replace duplicates with copy-on-write clones of the kept file
+)
+
+// duplicatePartialBlock is the block size of the partial checksum telling files of the same size apart
+const duplicatePartialBlock = 4 * 1024
+
+// DuplicateOptions controls FindDuplicates
+type DuplicateOptions struct {
+	Algo    enum.HashAlg     // checksum algorithm, SHA256 if not set
+	MinSize int64            // skip files smaller than this, empty files are always skipped
+	Action  enum.DedupAction // what to do with duplicates, report only if not set
+}
+
+// DuplicateGroup is a set of files with identical content
+type DuplicateGroup struct {
+	Size   int64    `json:"size"`   // size of each file
+	Sum    string   `json:"sum"`    // checksum of the content
+	Paths  []string `json:"paths"`  // sorted paths, the first one is kept when duplicates are replaced
+	Wasted int64    `json:"wasted"` // bytes taken by all but one of the files
+}
+
+// DuplicateReport is the result of FindDuplicates
+type DuplicateReport struct {
+	Groups    []DuplicateGroup `json:"groups"`    // groups sorted by wasted bytes, largest first
+	Wasted    int64            `json:"wasted"`    // total wasted bytes of all groups
+	Reclaimed int64            `json:"reclaimed"` // bytes freed by replacing duplicates
+}
+
+// duplicateCandidate is a file which may have duplicates
+type duplicateCandidate struct {
+	path string
+	info os.FileInfo
+}
+
+// FindDuplicates finds files with identical content in dirs. Files are grouped by size first, then by
+// a partial checksum of sampled blocks, see ChecksumFingerprint, and only files still sharing a group
+// get the full checksum, so most files are never read completely. Hard links to the same file are counted once,
+// as they take no extra space. Symlinks and special files are skipped, as well as paths listed twice.
+//
+// With opts.Action set, duplicates are replaced by hard links to, or reflinks (copy-on-write clones)
+// of the first file of each group. A hard link shares the mode and owner of the kept file, while a reflink keeps
+// those of the replaced one, but needs a filesystem supporting it, like btrfs or xfs on Linux.
+// A file is replaced atomically, and only if neither it nor the kept file changed size or modification time
+// since they were found. The first failed replacement stops the process, returning the report with groups found
+// and bytes reclaimed so far together with the error.
+func FindDuplicates(dirs []string, opts DuplicateOptions) (*DuplicateReport, error) {
+	if opts.Algo == (enum.HashAlg{}) {
+		opts.Algo = enum.HashAlgSHA256
+	}
+	if _, err := newHash(opts.Algo); err != nil {
+		return nil, err
+	}
+	if opts.MinSize < 1 {
+		opts.MinSize = 1
+	}
+
+	bySize, err := collectDuplicateCandidates(dirs, opts.MinSize)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := map[string]os.FileInfo{}
+	report := &DuplicateReport{}
+	for size, files := range bySize {
+		for _, f := range files {
+			infos[f.path] = f.info
+		}
+		groups, err := groupDuplicates(files, opts.Algo)
+		if err != nil {
+			return nil, err
+		}
+		for sum, paths := range groups {
+			sort.Strings(paths)
+			wasted := size * int64(len(paths)-1)
+			report.Groups = append(report.Groups, DuplicateGroup{Size: size, Sum: sum, Paths: paths, Wasted: wasted})
+			report.Wasted += wasted
+		}
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		if report.Groups[i].Wasted != report.Groups[j].Wasted {
+			return report.Groups[i].Wasted > report.Groups[j].Wasted
+		}
+		return report.Groups[i].Paths[0] < report.Groups[j].Paths[0]
+	})
+
+	if opts.Action == (enum.DedupAction{}) || opts.Action == enum.DedupActionNone {
+		return report, nil
+	}
+	for _, g := range report.Groups {
+		for _, path := range g.Paths[1:] {
+			if err := replaceDuplicate(g.Paths[0], path, infos, opts.Action); err != nil {
+				return report, err
+			}
+			report.Reclaimed += g.Size
+		}
+	}
+	return report, nil
+}
+
+// collectDuplicateCandidates walks dirs and returns regular files of at least minSize bytes by size,
+// leaving out sizes with a single file, other paths of an already found file and paths seen before
+func collectDuplicateCandidates(dirs []string, minSize int64) (map[int64][]duplicateCandidate, error) {
+	bySize := map[int64][]duplicateCandidate{}
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			absPath, err := filepath.Abs(path)
+			if err != nil {
+				return fmt.Errorf("can't get absolute path of %s: %w", path, err)
+			}
+			if seen[absPath] {
+				return nil
+			}
+			seen[absPath] = true
+
+			info, err := d.Info()
+			if err != nil {
+				return fmt.Errorf("can't stat %s: %w", path, err)
+			}
+			if info.Size() < minSize {
+				return nil
+			}
+			for _, c := range bySize[info.Size()] {
+				if os.SameFile(c.info, info) {
+					return nil // a hard link of a file found already
+				}
+			}
+			bySize[info.Size()] = append(bySize[info.Size()], duplicateCandidate{path: path, info: info})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk %s: %w", dir, err)
+		}
+	}
+
+	for size, files := range bySize {
+		if len(files) < 2 {
+			delete(bySize, size)
+		}
+	}
+	return bySize, nil
+}
+
+// groupDuplicates splits files of the same size into groups of identical content by checksum,
+// checking partial checksums first and leaving out files without duplicates. Files small enough
+// for the partial checksum to cover them completely are not read again, except one of each group
+// for the checksum of the group.
+func groupDuplicates(files []duplicateCandidate, algo enum.HashAlg) (map[string][]string, error) {
+	whole := len(files) > 0 && files[0].info.Size() <= 3*duplicatePartialBlock
+	byPartial := map[string][]string{}
+	for _, f := range files {
+		sum, err := ChecksumFingerprint(f.path, algo, duplicatePartialBlock)
+		if err != nil {
+			return nil, err
+		}
+		byPartial[sum] = append(byPartial[sum], f.path)
+	}
+
+	res := map[string][]string{}
+	for _, paths := range byPartial {
+		if len(paths) < 2 {
+			continue
+		}
+		if whole {
+			sum, err := Checksum(paths[0], algo)
+			if err != nil {
+				return nil, err
+			}
+			res[sum] = append(res[sum], paths...)
+			continue
+		}
+		for _, path := range paths {
+			sum, err := Checksum(path, algo)
+			if err != nil {
+				return nil, err
+			}
+			res[sum] = append(res[sum], path)
+		}
+	}
+	for sum, paths := range res {
+		if len(paths) < 2 {
+			delete(res, sum)
+		}
+	}
+	return res, nil
+}
+
+// replaceDuplicate replaces path with a hard link to, or a reflink of keep, preparing it under a temporary
+// name next to path and renaming it over path, if neither file changed since found, as recorded in infos
+func replaceDuplicate(keep, path string, infos map[string]os.FileInfo, action enum.DedupAction) error {
+	keepInfo, err := os.Lstat(keep)
+	if err != nil {
+		return fmt.Errorf("can't stat %s: %w", keep, err)
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return fmt.Errorf("can't stat %s: %w", path, err)
+	}
+	for _, f := range []struct {
+		path      string
+		was, info os.FileInfo
+	}{{keep, infos[keep], keepInfo}, {path, infos[path], info}} {
+		if !f.info.Mode().IsRegular() || f.info.Size() != f.was.Size() || !f.info.ModTime().Equal(f.was.ModTime()) {
+			return fmt.Errorf("%s changed since it was hashed", f.path)
+		}
+	}
+
+	var tmpName string
+	switch action {
+	case enum.DedupActionHardlink:
+		if tmpName, err = TempFileName(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp"); err != nil {
+			return err
+		}
+		if err = os.Link(keep, tmpName); err != nil {
+			return fmt.Errorf("failed to link %s: %w", keep, err)
+		}
+	case enum.DedupActionReflink:
+		if tmpName, err = reflinkTemp(keep, path, info); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported dedup action %q", action)
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// reflinkTemp makes a reflink of keep next to path, with the mode, owner and modification time of path,
+// returning its temporary name
+func reflinkTemp(keep, path string, info os.FileInfo) (string, error) {
+	src, err := os.Open(keep) //nolint:gosec // keep is a path found by FindDuplicates
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", keep, err)
+	}
+	defer func() { _ = src.Close() }()
+
+	f, err := createTempSibling(path, 0o600)
+	if err != nil {
+		return "", err
+	}
+	tmpName := f.Name()
+	err = cloneFile(f, src)
+	if err == nil {
+		err = copyFileAttrs(f, info)
+	}
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chtimes(tmpName, info.ModTime(), info.ModTime())
+	}
+	if err != nil {
+		_ = os.Remove(tmpName)
+		return "", fmt.Errorf("failed to reflink %s to %s: %w", keep, path, err)
+	}
+	return tmpName, nil
+}
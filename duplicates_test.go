@@ -0,0 +1,184 @@
+package fileutils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+// writeDuplicatesTree creates two directories with duplicate files, returning them
+func writeDuplicatesTree(t *testing.T) (dirA, dirB string) {
+	t.Helper()
+	root := t.TempDir()
+	dirA, dirB = filepath.Join(root, "a"), filepath.Join(root, "b")
+	big := strings.Repeat("0123456789", 2000) // larger than three partial blocks
+	writeTestTree(t, dirA, map[string]string{
+		"one.txt":     "same content",
+		"big.bin":     big,
+		"big-alt.bin": big[:5000] + "X" + big[5001:], // same size and sampled blocks, differs in the middle
+		"unique.txt":  "unique content",
+		"empty1":      "",
+		"sub/two.txt": "same content",
+	})
+	writeTestTree(t, dirB, map[string]string{
+		"three.txt": "same content",
+		"big.bin":   big,
+		"empty2":    "",
+		"other.txt": "same size!!!",
+	})
+	return dirA, dirB
+}
+
+func TestFindDuplicates(t *testing.T) {
+	dirA, dirB := writeDuplicatesTree(t)
+
+	report, err := FindDuplicates([]string{dirA, dirB, dirA}, DuplicateOptions{})
+	require.NoError(t, err)
+	sum, err := Checksum(filepath.Join(dirA, "one.txt"), enum.HashAlgSHA256)
+	require.NoError(t, err)
+	bigSum, err := Checksum(filepath.Join(dirA, "big.bin"), enum.HashAlgSHA256)
+	require.NoError(t, err)
+
+	assert.Equal(t, &DuplicateReport{
+		Groups: []DuplicateGroup{
+			{Size: 20000, Sum: bigSum, Paths: []string{filepath.Join(dirA, "big.bin"), filepath.Join(dirB, "big.bin")}, Wasted: 20000},
+			{Size: 12, Sum: sum, Paths: []string{filepath.Join(dirA, "one.txt"), filepath.Join(dirA, "sub", "two.txt"),
+				filepath.Join(dirB, "three.txt")}, Wasted: 24},
+		},
+		Wasted: 20024,
+	}, report)
+
+	t.Run("min size and algorithm", func(t *testing.T) {
+		report, err := FindDuplicates([]string{dirA, dirB}, DuplicateOptions{MinSize: 100, Algo: enum.HashAlgMD5})
+		require.NoError(t, err)
+		require.Len(t, report.Groups, 1)
+		assert.Len(t, report.Groups[0].Sum, 32)
+		assert.Equal(t, int64(20000), report.Wasted)
+	})
+
+	t.Run("hard links counted once", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestTree(t, dir, map[string]string{"a.txt": "linked content"})
+		require.NoError(t, os.Link(filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")))
+		report, err := FindDuplicates([]string{dir}, DuplicateOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, report.Groups)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := FindDuplicates([]string{filepath.Join(dirA, "missing")}, DuplicateOptions{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to walk")
+	})
+}
+
+func TestFindDuplicatesHardlink(t *testing.T) {
+	dirA, dirB := writeDuplicatesTree(t)
+
+	report, err := FindDuplicates([]string{dirA, dirB}, DuplicateOptions{Action: enum.DedupActionHardlink})
+	require.NoError(t, err)
+	assert.Equal(t, int64(20024), report.Reclaimed)
+
+	keep, err := os.Stat(filepath.Join(dirA, "one.txt"))
+	require.NoError(t, err)
+	for _, path := range []string{filepath.Join(dirA, "sub", "two.txt"), filepath.Join(dirB, "three.txt")} {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.True(t, os.SameFile(keep, info), path)
+	}
+	assertOnlyFiles(t, dirB, "big.bin", "empty2", "other.txt", "three.txt")
+
+	// nothing left to reclaim
+	report, err = FindDuplicates([]string{dirA, dirB}, DuplicateOptions{Action: enum.DedupActionHardlink})
+	require.NoError(t, err)
+	assert.Empty(t, report.Groups)
+	assert.Zero(t, report.Reclaimed)
+
+	t.Run("changed file is not replaced", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestTree(t, dir, map[string]string{"a.txt": "content", "b.txt": "content"})
+		keep, path := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+		infos := map[string]os.FileInfo{}
+		for _, p := range []string{keep, path} {
+			info, err := os.Lstat(p)
+			require.NoError(t, err)
+			infos[p] = info
+		}
+		require.NoError(t, os.WriteFile(path, []byte("changed"), 0o600))
+
+		err := replaceDuplicate(keep, path, infos, enum.DedupActionHardlink)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "changed since it was hashed")
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "changed", string(data))
+	})
+}
+
+func TestFindDuplicatesReflink(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("reflinks are supported on linux only")
+	}
+	dirA, dirB := writeDuplicatesTree(t)
+	require.NoError(t, os.Chmod(filepath.Join(dirB, "three.txt"), 0o640))
+
+	report, err := FindDuplicates([]string{dirA, dirB}, DuplicateOptions{Action: enum.DedupActionReflink})
+	if err != nil {
+		// most filesystems, like ext4 and tmpfs, can't clone files, the duplicates stay as they are
+		assert.Contains(t, err.Error(), "failed to reflink")
+		assert.Zero(t, report.Reclaimed)
+		assertOnlyFiles(t, dirB, "big.bin", "empty2", "other.txt", "three.txt")
+		data, err := os.ReadFile(filepath.Join(dirB, "big.bin"))
+		require.NoError(t, err)
+		assert.Len(t, data, 20000)
+		return
+	}
+
+	assert.Equal(t, int64(20024), report.Reclaimed)
+	info, err := os.Stat(filepath.Join(dirB, "three.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o640), info.Mode().Perm(), "reflink keeps the mode of the replaced file")
+	keep, err := os.Stat(filepath.Join(dirA, "one.txt"))
+	require.NoError(t, err)
+	assert.False(t, os.SameFile(keep, info))
+	data, err := os.ReadFile(filepath.Join(dirB, "three.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "same content", string(data))
+}
+
+func TestGroupDuplicates(t *testing.T) {
+	// files differing in a single byte, not sampled by the partial checksum of a file larger than three blocks
+	candidates := func(t *testing.T, size int) []duplicateCandidate {
+		dir := t.TempDir()
+		content := bytes.Repeat([]byte("x"), size)
+		var res []duplicateCandidate
+		for i, name := range []string{"a.bin", "b.bin", "c.bin"} {
+			if i == 2 {
+				content[2*duplicatePartialBlock] = 'y'
+			}
+			path := filepath.Join(dir, name)
+			require.NoError(t, os.WriteFile(path, content, 0o600))
+			info, err := os.Stat(path)
+			require.NoError(t, err)
+			res = append(res, duplicateCandidate{path: path, info: info})
+		}
+		return res
+	}
+
+	for _, size := range []int{3 * duplicatePartialBlock, 3*duplicatePartialBlock + 1} {
+		files := candidates(t, size)
+		groups, err := groupDuplicates(files, enum.HashAlgSHA256)
+		require.NoError(t, err)
+		sum, err := Checksum(files[0].path, enum.HashAlgSHA256)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{sum: {files[0].path, files[1].path}}, groups, "size %d", size)
+	}
+}
@@ -66,6 +66,8 @@ func ParseEventType(v string) (EventType, error) {
 		return EventTypeChmod, nil
 	case strings.ToLower("Create"):
 		return EventTypeCreate, nil
+	case strings.ToLower("Move"):
+		return EventTypeMove, nil
 	case strings.ToLower("Remove"):
 		return EventTypeRemove, nil
 	case strings.ToLower("Rename"):
@@ -91,6 +93,7 @@ func MustEventType(v string) EventType {
 var (
 	EventTypeChmod  = EventType{name: "Chmod", value: 4}
 	EventTypeCreate = EventType{name: "Create", value: 0}
+	EventTypeMove   = EventType{name: "Move", value: 5}
 	EventTypeRemove = EventType{name: "Remove", value: 2}
 	EventTypeRename = EventType{name: "Rename", value: 3}
 	EventTypeWrite  = EventType{name: "Write", value: 1}
@@ -101,6 +104,7 @@ func EventTypeValues() []EventType {
 	return []EventType{
 		EventTypeChmod,
 		EventTypeCreate,
+		EventTypeMove,
 		EventTypeRemove,
 		EventTypeRename,
 		EventTypeWrite,
@@ -112,6 +116,7 @@ func EventTypeNames() []string {
 	return []string{
 		"Chmod",
 		"Create",
+		"Move",
 		"Remove",
 		"Rename",
 		"Write",
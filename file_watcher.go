@@ -1,10 +1,16 @@
 package fileutils
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io/fs"
+	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 
@@ -27,23 +33,284 @@ const (
 	eventTypeRemove
 	eventTypeRename
 	eventTypeChmod
+	eventTypeMove // moved within the watched paths, from FileEvent.OldPath
 )
 
-// FileEvent represents a file system event
+//go:generate enum -type=deliveryPolicy -path=enum
+
+// deliveryPolicy is what a channel based watcher does when the consumer doesn't keep up
+//
+//nolint:unused // This type is used by the enum generator
+type deliveryPolicy int
+
+// Delivery policies
+//
+//nolint:unused // These constants are used by the enum generator
+const (
+	deliveryPolicyBlock deliveryPolicy = iota + 1 // wait for room in the channel, holding back further events
+	deliveryPolicyDrop                            // drop what doesn't fit into the channel
+)
+
+// defaultWatchBuffer is the size of the channels of a channel based watcher if not set with WithBuffer
+const defaultWatchBuffer = 64
+
+// FileEvent represents a file system event. A file or directory moved is reported with a Rename event
+// of the old path and a Create event of the new one, see WithMoves to get a single Move event instead.
 type FileEvent struct {
-	Path string         // path to the file or directory
-	Type enum.EventType // type of event
+	Path    string           // path to the file or directory
+	OldPath string           // path the file or directory was moved from, set for Move events only
+	Type    enum.EventType   // type of event
+	Ops     []enum.EventType // all operations of the event in a fixed order, including Type, see WithDebounce for the Type of merged events
+}
+
+// Has checks if op is one of the operations of the event
+func (e FileEvent) Has(op enum.EventType) bool {
+	return e.Type == op || containsEventType(e.Ops, op)
+}
+
+// fsnotifyOps maps fsnotify operations to event types, in the order of FileEvent.Ops
+var fsnotifyOps = []struct {
+	op  fsnotify.Op
+	typ enum.EventType
+}{
+	{fsnotify.Create, enum.EventTypeCreate},
+	{fsnotify.Write, enum.EventTypeWrite},
+	{fsnotify.Remove, enum.EventTypeRemove},
+	{fsnotify.Rename, enum.EventTypeRename},
+	{fsnotify.Chmod, enum.EventTypeChmod},
 }
 
+// newFileEvent converts an event of the backend, which can combine several operations, e.g. Create|Write,
+// returning false if it has none of the known operations. A Create of a path renamed from another one is a Move.
+func newFileEvent(event watchEvent) (FileEvent, bool) {
+	res := FileEvent{Path: event.Name}
+	for _, o := range fsnotifyOps {
+		if event.Has(o.op) {
+			res.Ops = append(res.Ops, o.typ)
+		}
+	}
+	if len(res.Ops) == 0 {
+		return res, false
+	}
+	res.Type = res.Ops[0]
+	if event.oldPath != "" && res.Type == enum.EventTypeCreate {
+		res.OldPath, res.Type, res.Ops = event.oldPath, enum.EventTypeMove, []enum.EventType{enum.EventTypeMove}
+	}
+	return res, true
+}
+
+var (
+	// ErrEventOverflow is reported to the error handler when the system event queue overflowed and events were lost,
+	// so the watched paths have to be rescanned to catch up with the changes
+	ErrEventOverflow = errors.New("file watcher event queue overflow")
+
+	// ErrEventDropped is reported by a channel based watcher with the drop policy for events
+	// which didn't fit into the events channel
+	ErrEventDropped = errors.New("file watcher event dropped")
+)
+
 // FileWatcher watches for file system events
 type FileWatcher struct {
-	watcher  *fsnotify.Watcher
+	watcher  watchBackend
 	callback func(FileEvent)
-	done     chan struct{}
+	opts     watchOptions
+	done     chan struct{} // closed by Close
+	stopped  chan struct{} // closed when the watch loop exits
+
+	closeOnce sync.Once
+	closeErr  error
+
+	debouncer *eventDebouncer // set with WithDebounce or WithBatch only
+	pairer    *renamePairer
+
+	events chan FileEvent // set for channel based watchers only
+	errs   chan error     // set for channel based watchers only
+
+	recursive bool             // watch new subdirectories, set by WatchRecursive and WatchRecursiveChan
+	dirsMu    sync.Mutex       // protects dirs and dirChanges
+	dirs      map[string]bool  // directories watched by a recursive watcher
+	newDirs   chan dirContents // contents of new directories, found outside of the watch loop
+
+	dirChanges []dirChange   // watch changes of a recursive watcher waiting for changeDirs, in order
+	dirsQueued chan struct{} // signals changeDirs that dirChanges were queued
+}
+
+// dirChange is a change of the watches of a recursive watcher, adding a new directory or removing
+// the watches of a gone one
+type dirChange struct {
+	add    string   // directory to watch with all subdirectories
+	remove []string // watched directories to drop
+}
+
+// dirContents is what a recursive watcher found in a new directory when it started watching it
+type dirContents struct {
+	events []FileEvent // Create events for the files and subdirectories
+	err    error
+}
+
+// watchBackend is the source of file system events of a FileWatcher, fsnotify or a poller set with WithPolling
+type watchBackend interface {
+	Add(path string) error
+	Remove(path string) error
+	Close() error
+	Events() <-chan watchEvent
+	Errors() <-chan error
+}
+
+// watchEvent is an event of a watchBackend
+type watchEvent struct {
+	fsnotify.Event
+	oldPath string // path a created file was renamed from, if the backend knows it
+	moved   bool   // the event reports a whole move, the old path has no Rename event of its own
+}
+
+// fsnotifyBackend is the watchBackend of system notifications
+type fsnotifyBackend struct {
+	watcher   *fsnotify.Watcher
+	events    chan watchEvent
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newFsnotifyBackend makes a fsnotify watcher and starts passing its events on
+func newFsnotifyBackend() (*fsnotifyBackend, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	b := &fsnotifyBackend{watcher: watcher, events: make(chan watchEvent), done: make(chan struct{})}
+	go b.forward()
+	return b, nil
+}
+
+// forward passes fsnotify events to the events channel with the old paths of renamed files, until it's closed
+func (b *fsnotifyBackend) forward() {
+	defer close(b.events)
+	for event := range b.watcher.Events {
+		select {
+		case b.events <- watchEvent{Event: event, oldPath: renamedFrom(event)}:
+		case <-b.done:
+			return
+		}
+	}
+}
+
+func (b *fsnotifyBackend) Add(path string) error     { return b.watcher.Add(path) }
+func (b *fsnotifyBackend) Remove(path string) error  { return b.watcher.Remove(path) }
+func (b *fsnotifyBackend) Events() <-chan watchEvent { return b.events }
+func (b *fsnotifyBackend) Errors() <-chan error      { return b.watcher.Errors }
+
+// Close stops fsnotify, it can be called more than once
+func (b *fsnotifyBackend) Close() error {
+	b.closeOnce.Do(func() { close(b.done) })
+	return b.watcher.Close()
+}
+
+// WatchOption configures a FileWatcher
+type WatchOption func(*watchOptions)
+
+// watchOptions holds the settings made by WatchOption
+type watchOptions struct {
+	errorHandler func(error)                  // called with errors of the watcher
+	bufferSize   int                          // size of the channels of a channel based watcher
+	policy       enum.DeliveryPolicy          // what a channel based watcher does when a channel is full
+	debounce     time.Duration                // quiet window of merged events
+	batch        func([]FileEvent)            // called with all merged events of a window
+	pollInterval time.Duration                // interval of the poller, set by WithPolling or WithPollingFallback
+	pollFallback bool                         // poll only if fsnotify fails
+	pollAlgo     enum.HashAlg                 // checksum algorithm of the poller, no checksums if not set
+	moves        bool                         // report moves with single Move events
+	guessMoves   bool                         // pair renames and creates by inode where the system doesn't pair them
+	newBackend   func() (watchBackend, error) // makes the backend of system notifications
+}
+
+// WithMoves makes the watcher report a file or directory moved within the watched paths with a single Move event
+// of the new path carrying the old one in OldPath, instead of a Rename of the old path and a Create of the new one.
+// Moves are known on linux and windows, where Rename events are held for up to 50ms to be paired with the Create,
+// and with polling on unix systems, which have inodes to find moves by, see WithPolling. Elsewhere they are
+// still reported with two events, unless guessed with WithMoveGuessing.
+func WithMoves() WatchOption {
+	return func(o *watchOptions) { o.moves = true }
+}
+
+// WithMoveGuessing is WithMoves, also guessing moves where the system doesn't report them, like on macOS and BSD.
+// It's a best-effort heuristic: a Rename is held for up to 50ms and paired with a Create of a file with the same
+// device, inode and size as the old path had when last seen by the watcher, on unix only. The watcher sees files
+// with an Lstat of every watched file when it starts and of every path with an event, moves of files changed
+// in between are still reported with two events.
+func WithMoveGuessing() WatchOption {
+	return func(o *watchOptions) { o.moves, o.guessMoves = true, true }
+}
+
+// WithPolling makes the watcher poll the watched paths every interval, 1s if not positive, instead of
+// using system notifications, which don't work on network and FUSE file systems, some container overlays
+// and big trees exceeding the limit of watches. Files are compared by size, modification time and, on unix, inode,
+// see WithPollingChecksum to compare contents too. Changes undone between two polls are not reported,
+// a file replaced by another one is reported as created.
+func WithPolling(interval time.Duration) WatchOption {
+	return func(o *watchOptions) {
+		if interval <= 0 {
+			interval = defaultPollInterval
+		}
+		o.pollInterval, o.pollFallback = interval, false
+	}
+}
+
+// WithPollingFallback makes the watcher poll the watched paths every interval, see WithPolling,
+// if system notifications fail to start, creating the watcher or watching the paths. Paths added later,
+// like new directories of a recursive watcher, are polled if the system runs out of watches for them.
+func WithPollingFallback(interval time.Duration) WatchOption {
+	return func(o *watchOptions) {
+		if interval <= 0 {
+			interval = defaultPollInterval
+		}
+		o.pollInterval, o.pollFallback = interval, true
+	}
+}
+
+// WithPollingChecksum makes the polling watcher compare checksums of files with unchanged size,
+// modification time and inode too, catching changes these miss, at the cost of reading every watched file
+// on each poll
+func WithPollingChecksum(algo enum.HashAlg) WatchOption {
+	return func(o *watchOptions) { o.pollAlgo = algo }
+}
+
+// WithErrorHandler sets the function called with errors of the watcher. Errors matching ErrEventOverflow
+// with errors.Is mean events were lost. Without the option errors are logged with the standard logger,
+// a nil handler drops them. Channel based watchers send errors to their Errors channel instead.
+func WithErrorHandler(fn func(error)) WatchOption {
+	return func(o *watchOptions) { o.errorHandler = fn }
+}
+
+// WithBuffer sets the size of the Events and Errors channels of a channel based watcher, 64 by default,
+// and what to do when the consumer doesn't keep up and a channel is full. With enum.DeliveryPolicyBlock,
+// the default, the watcher waits, holding back further events, with enum.DeliveryPolicyDrop it drops
+// the event, reporting ErrEventDropped to the Errors channel if there is room for it.
+func WithBuffer(size int, policy enum.DeliveryPolicy) WatchOption {
+	return func(o *watchOptions) {
+		if size < 0 {
+			size = 0
+		}
+		o.bufferSize, o.policy = size, policy
+	}
+}
+
+// newWatchOptions applies opts to the defaults
+func newWatchOptions(opts []WatchOption) watchOptions {
+	res := watchOptions{
+		errorHandler: func(err error) { log.Printf("[WARN] file watcher error: %v", err) },
+		bufferSize:   defaultWatchBuffer,
+		policy:       enum.DeliveryPolicyBlock,
+		newBackend:   func() (watchBackend, error) { return newFsnotifyBackend() },
+	}
+	for _, opt := range opts {
+		opt(&res)
+	}
+	return res
 }
 
 // NewFileWatcher creates a new file watcher for the specified path
-func NewFileWatcher(path string, callback func(FileEvent)) (*FileWatcher, error) {
+func NewFileWatcher(path string, callback func(FileEvent), opts ...WatchOption) (*FileWatcher, error) {
 	if path == "" {
 		return nil, errors.New("empty path")
 	}
@@ -52,73 +319,206 @@ func NewFileWatcher(path string, callback func(FileEvent)) (*FileWatcher, error)
 		return nil, errors.New("callback function is required")
 	}
 
+	return newFileWatcher(path, false, callback, opts)
+}
+
+// NewFileWatcherChan creates a new file watcher for the specified path, delivering events and errors
+// to the Events and Errors channels instead of calling a function, so a slow consumer doesn't hold back
+// the watcher until the buffer, set with WithBuffer, is full. Both channels have to be read,
+// they are closed when the watcher stops.
+func NewFileWatcherChan(path string, opts ...WatchOption) (*FileWatcher, error) {
+	if path == "" {
+		return nil, errors.New("empty path")
+	}
+
+	return newFileWatcher(path, false, nil, opts)
+}
+
+// newFileWatcher creates and starts a watcher of path, or of dir with all subdirectories if recursive is set.
+// A nil callback makes a channel based watcher.
+func newFileWatcher(path string, recursive bool, callback func(FileEvent), opts []WatchOption) (*FileWatcher, error) {
 	// check if path exists
+	if recursive && !IsDir(path) {
+		return nil, fmt.Errorf("directory does not exist: %s", path)
+	}
 	if !IsFile(path) && !IsDir(path) {
 		return nil, fmt.Errorf("path does not exist: %s", path)
 	}
 
-	// create fsnotify watcher
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create watcher: %w", err)
+	fw := &FileWatcher{
+		callback:  callback,
+		opts:      newWatchOptions(opts),
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+		recursive: recursive,
 	}
-
-	// add path to watcher
-	if err := watcher.Add(path); err != nil {
-		_ = watcher.Close()
-		return nil, fmt.Errorf("failed to watch path %s: %w", path, err)
+	if recursive {
+		fw.newDirs = make(chan dirContents)
+		fw.dirsQueued = make(chan struct{}, 1)
+	}
+	if callback == nil {
+		fw.events = make(chan FileEvent, fw.opts.bufferSize)
+		fw.errs = make(chan error, fw.opts.bufferSize)
+		fw.callback = fw.sendEvent
+		fw.opts.errorHandler = fw.sendError
+	}
+	if fw.opts.debounce > 0 || fw.opts.batch != nil {
+		window := fw.opts.debounce
+		if window <= 0 {
+			window = defaultDebounceWindow
+		}
+		fw.debouncer = newEventDebouncer(window, fw.opts.batch != nil)
 	}
 
-	fw := &FileWatcher{
-		watcher:  watcher,
-		callback: callback,
-		done:     make(chan struct{}),
+	err := fw.start(path, fw.opts.pollInterval > 0 && !fw.opts.pollFallback)
+	if err != nil && fw.opts.pollFallback {
+		err = fw.start(path, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if fw.opts.moves && (renamesPaired || fw.opts.guessMoves) {
+		fw.pairer = newRenamePairer(moveWindow, fw.opts.guessMoves)
+		fw.pairer.scan(path, recursive)
 	}
 
 	// start watching in a goroutine
 	go fw.watch()
+	if recursive {
+		go fw.changeDirs()
+	}
 
 	return fw, nil
 }
 
-// watch processes events from the fsnotify watcher
+// start creates the backend, fsnotify or a poller if poll is set, and adds path to it.
+// With WithPollingFallback fsnotify falls back to the poller for paths it has no watches left for.
+func (fw *FileWatcher) start(path string, poll bool) error {
+	if poll {
+		fw.watcher = newPoller(fw.opts.pollInterval, fw.opts.pollAlgo)
+	} else {
+		watcher, err := fw.opts.newBackend()
+		if err != nil {
+			return fmt.Errorf("failed to create watcher: %w", err)
+		}
+		fw.watcher = watcher
+		if fw.opts.pollFallback {
+			fw.watcher = newFallbackBackend(watcher, newPoller(fw.opts.pollInterval, fw.opts.pollAlgo))
+		}
+	}
+
+	var err error
+	if fw.recursive {
+		fw.dirs = map[string]bool{}
+		err = fw.addRecursive(path)
+	} else if err = fw.watcher.Add(path); err != nil {
+		err = fmt.Errorf("failed to watch path %s: %w", path, err)
+	}
+	if err != nil {
+		_ = fw.watcher.Close()
+		return err
+	}
+	return nil
+}
+
+// watch processes events from the backend
 func (fw *FileWatcher) watch() {
+	defer func() {
+		if fw.events != nil { // nothing is sent to the channels from now on
+			close(fw.events)
+			close(fw.errs)
+		}
+		close(fw.stopped)
+	}()
+
+	// the timer for the next held rename or merged event to be due
+	var timer *time.Timer
+	var timerCh <-chan time.Time
+	resetTimer := func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		timerCh = nil
+		now := time.Now()
+		var wait time.Duration
+		var ok bool
+		if fw.pairer != nil {
+			wait, ok = fw.pairer.next(now)
+		}
+		if fw.debouncer != nil {
+			if dw, dok := fw.debouncer.next(now); dok && (!ok || dw < wait) {
+				wait, ok = dw, true
+			}
+		}
+		if ok {
+			timer = time.NewTimer(wait)
+			timerCh = timer.C
+		}
+	}
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	// pass an event to the callback, or hold it in the debouncer
+	handle := func(event FileEvent) {
+		if fw.debouncer == nil {
+			fw.callback(event)
+			return
+		}
+		fw.debouncer.add(event, time.Now())
+		resetTimer()
+	}
+
 	for {
 		select {
-		case event, ok := <-fw.watcher.Events:
+		case event, ok := <-fw.watcher.Events():
 			if !ok {
 				return
 			}
 
-			// convert fsnotify event to our event type
-			var eventType enum.EventType
-			switch {
-			case event.Has(fsnotify.Create):
-				eventType = enum.EventTypeCreate
-			case event.Has(fsnotify.Write):
-				eventType = enum.EventTypeWrite
-			case event.Has(fsnotify.Remove):
-				eventType = enum.EventTypeRemove
-			case event.Has(fsnotify.Rename):
-				eventType = enum.EventTypeRename
-			case event.Has(fsnotify.Chmod):
-				eventType = enum.EventTypeChmod
-			default:
-				continue // unknown event type
+			for _, fileEvent := range fw.fileEvents(event) {
+				if fw.pairer == nil || event.moved { // nothing to pair
+					handle(fileEvent)
+				} else {
+					for _, e := range fw.pairer.add(fileEvent, time.Now()) {
+						handle(e)
+					}
+				}
+				if fw.recursive {
+					fw.trackDirs(fileEvent)
+				}
+			}
+			resetTimer()
+
+		case found := <-fw.newDirs:
+			for _, event := range found.events {
+				if fw.pairer != nil {
+					fw.pairer.record(event.Path)
+				}
+				handle(event)
+			}
+			if found.err != nil {
+				fw.handleError(found.err)
 			}
 
-			// call the callback with the event
-			fw.callback(FileEvent{
-				Path: event.Name,
-				Type: eventType,
-			})
+		case <-timerCh:
+			if fw.pairer != nil {
+				for _, e := range fw.pairer.due(time.Now()) {
+					handle(e)
+				}
+			}
+			if fw.debouncer != nil {
+				fw.deliver(fw.debouncer.due(time.Now()))
+			}
+			resetTimer()
 
-		case err, ok := <-fw.watcher.Errors:
+		case err, ok := <-fw.watcher.Errors():
 			if !ok {
 				return
 			}
-			// log error or call error callback
-			fmt.Printf("error: %v\n", err)
+			fw.handleError(err)
 
 		case <-fw.done:
 			return
@@ -126,10 +526,128 @@ func (fw *FileWatcher) watch() {
 	}
 }
 
-// Close stops watching and releases resources
+// fileEvents converts an event of the backend to the events of the watcher, none for unknown operations.
+// Without WithMoves the old path of a renamed file is dropped, and a whole move reported by the poller
+// is split into a Rename of the old path and a Create of the new one.
+func (fw *FileWatcher) fileEvents(event watchEvent) []FileEvent {
+	var res []FileEvent
+	if !fw.opts.moves {
+		if event.moved {
+			res = append(res, FileEvent{Path: event.oldPath, Type: enum.EventTypeRename, Ops: []enum.EventType{enum.EventTypeRename}})
+		}
+		event.oldPath = ""
+	}
+	if fileEvent, ok := newFileEvent(event); ok {
+		res = append(res, fileEvent)
+	}
+	return res
+}
+
+// deliver passes events merged by the debouncer to the batch function or to the callback one by one
+func (fw *FileWatcher) deliver(events []FileEvent) {
+	if len(events) == 0 {
+		return
+	}
+	if fw.opts.batch != nil {
+		fw.opts.batch(events)
+		return
+	}
+	for _, event := range events {
+		fw.callback(event)
+	}
+}
+
+// handleError passes an error of the watcher to the error handler, marking a lost events error with ErrEventOverflow
+func (fw *FileWatcher) handleError(err error) {
+	if errors.Is(err, fsnotify.ErrEventOverflow) {
+		err = &overflowError{err: err}
+	}
+	if fw.opts.errorHandler != nil {
+		fw.opts.errorHandler(err)
+	}
+}
+
+// overflowError is a lost events error of fsnotify, matching both ErrEventOverflow and the original error
+type overflowError struct {
+	err error
+}
+
+func (e *overflowError) Error() string        { return ErrEventOverflow.Error() + ": " + e.err.Error() }
+func (e *overflowError) Unwrap() error        { return e.err }
+func (e *overflowError) Is(target error) bool { return target == ErrEventOverflow }
+
+// sendEvent delivers an event to the events channel according to the delivery policy
+func (fw *FileWatcher) sendEvent(event FileEvent) {
+	if fw.opts.policy == enum.DeliveryPolicyDrop {
+		select {
+		case fw.events <- event:
+		default:
+			fw.sendError(fmt.Errorf("%w: %s %s", ErrEventDropped, event.Type, event.Path))
+		}
+		return
+	}
+
+	select {
+	case fw.events <- event:
+	case <-fw.done:
+	}
+}
+
+// sendError delivers an error to the errors channel according to the delivery policy
+func (fw *FileWatcher) sendError(err error) {
+	if fw.opts.policy == enum.DeliveryPolicyDrop {
+		select {
+		case fw.errs <- err:
+		default:
+		}
+		return
+	}
+
+	select {
+	case fw.errs <- err:
+	case <-fw.done:
+	}
+}
+
+// Events returns the channel events of a watcher made by NewFileWatcherChan or WatchRecursiveChan
+// are delivered to, nil for other watchers. The channel is closed when the watcher stops.
+func (fw *FileWatcher) Events() <-chan FileEvent {
+	if fw.events == nil {
+		return nil
+	}
+	return fw.events
+}
+
+// Errors returns the channel errors of a watcher made by NewFileWatcherChan or WatchRecursiveChan
+// are delivered to, nil for other watchers. The channel is closed when the watcher stops.
+func (fw *FileWatcher) Errors() <-chan error {
+	if fw.errs == nil {
+		return nil
+	}
+	return fw.errs
+}
+
+// Run blocks until ctx is canceled or the watcher stops, closing the watcher in the first case,
+// so its lifetime can be tied to a context. Returns the context error if ctx was canceled.
+func (fw *FileWatcher) Run(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		if err := fw.Close(); err != nil {
+			return err
+		}
+		return ctx.Err()
+	case <-fw.stopped:
+		return nil
+	}
+}
+
+// Close stops watching and releases resources, it can be called more than once
 func (fw *FileWatcher) Close() error {
-	close(fw.done)
-	return fw.watcher.Close()
+	fw.closeOnce.Do(func() {
+		close(fw.done)
+		fw.closeErr = fw.watcher.Close()
+	})
+	return fw.closeErr
 }
 
 // AddPath adds a path to the watcher
@@ -155,8 +673,11 @@ func (fw *FileWatcher) RemovePath(path string) error {
 	return fw.watcher.Remove(path)
 }
 
-// WatchRecursive watches a directory recursively
-func WatchRecursive(dir string, callback func(FileEvent)) (*FileWatcher, error) {
+// WatchRecursive watches a directory recursively. Directories created later are watched as they appear,
+// with Create events for everything found inside, which could be created before the watch was added,
+// so a file created right after its directory may be reported twice. Watches of removed or renamed
+// directories are dropped.
+func WatchRecursive(dir string, callback func(FileEvent), opts ...WatchOption) (*FileWatcher, error) {
 	if dir == "" {
 		return nil, errors.New("empty directory path")
 	}
@@ -165,44 +686,146 @@ func WatchRecursive(dir string, callback func(FileEvent)) (*FileWatcher, error)
 		return nil, errors.New("callback function is required")
 	}
 
-	// check if directory exists
-	if !IsDir(dir) {
-		return nil, fmt.Errorf("directory does not exist: %s", dir)
-	}
+	return newFileWatcher(dir, true, callback, opts)
+}
 
-	// create fsnotify watcher
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create watcher: %w", err)
+// WatchRecursiveChan watches a directory recursively, delivering events and errors to channels
+// the way NewFileWatcherChan does
+func WatchRecursiveChan(dir string, opts ...WatchOption) (*FileWatcher, error) {
+	if dir == "" {
+		return nil, errors.New("empty directory path")
 	}
 
-	// create file watcher
-	fw := &FileWatcher{
-		watcher:  watcher,
-		callback: callback,
-		done:     make(chan struct{}),
-	}
+	return newFileWatcher(dir, true, nil, opts)
+}
 
-	// add all subdirectories
-	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+// addRecursive adds dir with all subdirectories to the watcher
+func (fw *FileWatcher) addRecursive(dir string) error {
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 		if info.IsDir() {
-			if err := watcher.Add(path); err != nil {
+			if err := fw.watcher.Add(path); err != nil {
 				return fmt.Errorf("failed to watch directory %s: %w", path, err)
 			}
+			fw.dirsMu.Lock()
+			fw.dirs[path] = true
+			fw.dirsMu.Unlock()
 		}
 		return nil
 	})
-
 	if err != nil {
-		_ = watcher.Close()
-		return nil, fmt.Errorf("failed to set up recursive watching: %w", err)
+		return fmt.Errorf("failed to set up recursive watching: %w", err)
 	}
+	return nil
+}
 
-	// start watching in a goroutine
-	go fw.watch()
+// trackDirs starts watching a directory created in a recursive watcher and drops watches of a removed
+// or renamed one. Watches are changed outside of the watch loop, as fsnotify may wait for the loop
+// to take its pending events before it changes them, but in the order of the events, see changeDirs.
+func (fw *FileWatcher) trackDirs(event FileEvent) {
+	if event.Has(enum.EventTypeRemove) || event.Has(enum.EventTypeRename) {
+		fw.unwatchDir(event.Path)
+	}
+	if event.Has(enum.EventTypeMove) {
+		fw.unwatchDir(event.OldPath)
+	}
+	if !event.Has(enum.EventTypeCreate) && !event.Has(enum.EventTypeMove) {
+		return
+	}
+	if info, err := os.Lstat(event.Path); err == nil && info.IsDir() {
+		fw.queueDirChange(dirChange{add: event.Path})
+	}
+}
 
-	return fw, nil
+// queueDirChange queues a watch change for changeDirs, without waiting for it
+func (fw *FileWatcher) queueDirChange(change dirChange) {
+	fw.dirsMu.Lock()
+	fw.dirChanges = append(fw.dirChanges, change)
+	fw.dirsMu.Unlock()
+	select {
+	case fw.dirsQueued <- struct{}{}:
+	default: // signaled already
+	}
+}
+
+// changeDirs applies queued watch changes one by one until the watcher is closed. The order matters
+// for a renamed directory: inotify keeps the watches of its subdirectories, so they have to be removed
+// under the old paths before they are added under the new ones, not to drop the new watches.
+func (fw *FileWatcher) changeDirs() {
+	for {
+		select {
+		case <-fw.dirsQueued:
+		case <-fw.done:
+			return
+		}
+
+		fw.dirsMu.Lock()
+		changes := fw.dirChanges
+		fw.dirChanges = nil
+		fw.dirsMu.Unlock()
+		for _, change := range changes {
+			for _, path := range change.remove {
+				_ = fw.watcher.Remove(path) // watches of removed directories are dropped by the backend itself
+			}
+			if change.add != "" {
+				fw.watchNewDir(change.add)
+			}
+		}
+	}
+}
+
+// watchNewDir adds a new directory with all subdirectories to the watcher, passing Create events
+// for everything found inside to the watch loop
+func (fw *FileWatcher) watchNewDir(dir string) {
+	var found dirContents
+	found.err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil // removed meanwhile
+			}
+			return fmt.Errorf("failed to watch new directory %s: %w", dir, err)
+		}
+		if path != dir {
+			found.events = append(found.events, FileEvent{Path: path, Type: enum.EventTypeCreate,
+				Ops: []enum.EventType{enum.EventTypeCreate}})
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		// the directory is watched before it's read, so nothing created inside is missed
+		if err := fw.watcher.Add(path); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipDir
+			}
+			return fmt.Errorf("failed to watch directory %s: %w", path, err)
+		}
+		fw.dirsMu.Lock()
+		fw.dirs[path] = true
+		fw.dirsMu.Unlock()
+		return nil
+	})
+
+	select {
+	case fw.newDirs <- found:
+	case <-fw.done:
+	}
+}
+
+// unwatchDir drops the watches of a removed or renamed directory and all its subdirectories
+func (fw *FileWatcher) unwatchDir(dir string) {
+	prefix := dir + string(filepath.Separator)
+	var paths []string
+	fw.dirsMu.Lock()
+	for path := range fw.dirs {
+		if path == dir || strings.HasPrefix(path, prefix) {
+			paths = append(paths, path)
+			delete(fw.dirs, path)
+		}
+	}
+	fw.dirsMu.Unlock()
+	if len(paths) > 0 {
+		fw.queueDirChange(dirChange{remove: paths})
+	}
 }
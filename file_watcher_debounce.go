@@ -0,0 +1,165 @@
+package fileutils
+
+import (
+	"sort"
+	"time"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+// defaultDebounceWindow is the quiet window used by WithBatch if not set with WithDebounce
+const defaultDebounceWindow = 100 * time.Millisecond
+
+// debounceMaxWait is how many debounce windows an event is held at most, even if its path, or with a batch
+// function the watcher, never gets quiet
+const debounceMaxWait = 10
+
+// maxBatchEvents is the number of merged events passing a batch to the batch function without waiting
+const maxBatchEvents = 10000
+
+// WithDebounce makes the watcher hold events until their path is quiet for window, merging all events
+// of a path into one, with the operations of all of them in Ops. An editor saving a file with a write,
+// a chmod and a rename makes a single event this way. The Type of a merged event is the type of the first event,
+// unless a later one removed or renamed the path or created or moved it there again, then it's the type
+// of the last such event. A path changing all the time is delivered every ten windows at least.
+// Events held when the watcher is closed are dropped.
+func WithDebounce(window time.Duration) WatchOption {
+	return func(o *watchOptions) { o.debounce = window }
+}
+
+// WithBatch makes the watcher collect merged events, see WithDebounce, until the whole watcher is quiet
+// for the debounce window, 100ms if not set, and pass all of them to fn in one call, in the order
+// of their first events, instead of delivering them one by one. A batch is passed after ten windows
+// or with 10000 events at most, even if the watcher is not quiet.
+func WithBatch(fn func([]FileEvent)) WatchOption {
+	return func(o *watchOptions) { o.batch = fn }
+}
+
+// eventDebouncer holds events until their path, or with a batch function the whole watcher, is quiet for the window.
+// It is used by the watch loop only, so needs no locking.
+type eventDebouncer struct {
+	window  time.Duration
+	batch   bool
+	pending map[string]*pendingEvent
+	seq     int       // arrival counter keeping the order of events
+	first   time.Time // arrival of the first pending event of any path
+	last    time.Time // arrival of the last event of any path
+}
+
+// pendingEvent is a merged event waiting for its path to be quiet
+type pendingEvent struct {
+	event FileEvent
+	seq   int       // arrival order of the first event
+	first time.Time // arrival of the first event
+	last  time.Time // arrival of the last event
+}
+
+// newEventDebouncer makes a debouncer for the window, collecting batches of all paths if batch is set
+func newEventDebouncer(window time.Duration, batch bool) *eventDebouncer {
+	return &eventDebouncer{window: window, batch: batch, pending: map[string]*pendingEvent{}}
+}
+
+// add merges an event arrived at now into the pending event of its path
+func (d *eventDebouncer) add(event FileEvent, now time.Time) {
+	d.seq++
+	d.last = now
+	if len(d.pending) == 0 {
+		d.first = now
+	}
+	p, ok := d.pending[event.Path]
+	if !ok {
+		event.Ops = mergeEventOps(nil, event.Ops)
+		d.pending[event.Path] = &pendingEvent{event: event, seq: d.seq, first: now, last: now}
+		return
+	}
+
+	p.last = now
+	p.event.Ops = mergeEventOps(p.event.Ops, event.Ops)
+	switch {
+	case event.Type == enum.EventTypeRemove || event.Type == enum.EventTypeRename:
+		p.event.Type = event.Type // the path is gone
+	case event.Type == enum.EventTypeCreate:
+		p.event.Type = event.Type // the path is back, or new
+	case event.Type == enum.EventTypeMove:
+		p.event.Type, p.event.OldPath = event.Type, event.OldPath // the path is back, from another one
+	}
+}
+
+// due removes and returns events whose window or max wait is over at now, in the order of their first events
+func (d *eventDebouncer) due(now time.Time) []FileEvent {
+	var ready []*pendingEvent
+	full := d.batch && len(d.pending) >= maxBatchEvents
+	for path, p := range d.pending {
+		first, last := p.first, p.last
+		if d.batch {
+			first, last = d.first, d.last
+		}
+		if full || now.Sub(last) >= d.window || now.Sub(first) >= debounceMaxWait*d.window {
+			ready = append(ready, p)
+			delete(d.pending, path)
+		}
+	}
+	sort.Slice(ready, func(i, j int) bool { return ready[i].seq < ready[j].seq })
+
+	res := make([]FileEvent, len(ready))
+	for i, p := range ready {
+		res[i] = p.event
+	}
+	return res
+}
+
+// next returns the time from now until the next pending event is due, false if nothing is pending
+func (d *eventDebouncer) next(now time.Time) (time.Duration, bool) {
+	if len(d.pending) == 0 {
+		return 0, false
+	}
+	if d.batch {
+		if len(d.pending) >= maxBatchEvents {
+			return 0, true
+		}
+		return d.dueAt(d.first, d.last).Sub(now), true
+	}
+	var earliest time.Time
+	for _, p := range d.pending {
+		if at := d.dueAt(p.first, p.last); earliest.IsZero() || at.Before(earliest) {
+			earliest = at
+		}
+	}
+	return earliest.Sub(now), true
+}
+
+// dueAt returns when events first arrived at first and last at last are due
+func (d *eventDebouncer) dueAt(first, last time.Time) time.Time {
+	quiet, limit := last.Add(d.window), first.Add(debounceMaxWait*d.window)
+	if limit.Before(quiet) {
+		return limit
+	}
+	return quiet
+}
+
+// eventOpsOrder is the order of operations in FileEvent.Ops
+var eventOpsOrder = []enum.EventType{
+	enum.EventTypeCreate, enum.EventTypeWrite, enum.EventTypeRemove, enum.EventTypeRename, enum.EventTypeChmod,
+	enum.EventTypeMove,
+}
+
+// mergeEventOps returns the union of operations a and b in the order of FileEvent.Ops
+func mergeEventOps(a, b []enum.EventType) []enum.EventType {
+	res := make([]enum.EventType, 0, len(eventOpsOrder))
+	for _, op := range eventOpsOrder {
+		if containsEventType(a, op) || containsEventType(b, op) {
+			res = append(res, op)
+		}
+	}
+	return res
+}
+
+// containsEventType checks if ops contain op
+func containsEventType(ops []enum.EventType, op enum.EventType) bool {
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
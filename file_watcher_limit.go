@@ -0,0 +1,13 @@
+//go:build !plan9
+
+package fileutils
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isWatchLimitErr checks if the system failed to watch a path because it ran out of watches or open files
+func isWatchLimitErr(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EMFILE)
+}
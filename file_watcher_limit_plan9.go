@@ -0,0 +1,6 @@
+package fileutils
+
+// isWatchLimitErr always reports false, plan9 has no error numbers to tell the limit of watches by
+func isWatchLimitErr(error) bool {
+	return false
+}
@@ -0,0 +1,85 @@
+//go:build !plan9
+
+package fileutils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+// limitedBackend is a fsnotify backend out of watches for paths named full
+type limitedBackend struct {
+	*fsnotifyBackend
+}
+
+func (b limitedBackend) Add(path string) error {
+	if filepath.Base(path) == "full" {
+		return fmt.Errorf("failed to watch %s: %w", path, syscall.ENOSPC)
+	}
+	return b.fsnotifyBackend.Add(path)
+}
+
+func TestFileWatcherPollingFallback(t *testing.T) {
+	withLimitedBackend := func(o *watchOptions) {
+		o.newBackend = func() (watchBackend, error) {
+			b, err := newFsnotifyBackend()
+			if err != nil {
+				return nil, err
+			}
+			return limitedBackend{b}, nil
+		}
+	}
+
+	t.Run("new directory", func(t *testing.T) {
+		dir := t.TempDir()
+		watcher, err := WatchRecursiveChan(dir, WithPollingFallback(10*time.Millisecond), withLimitedBackend)
+		require.NoError(t, err)
+		defer func() { _ = watcher.Close() }()
+
+		full := filepath.Join(dir, "full")
+		require.NoError(t, os.Mkdir(full, 0o750))
+		waitForChanEvent(t, watcher, full)
+		backend := watcher.watcher.(*fallbackBackend)
+		require.Eventually(t, func() bool {
+			backend.mu.Lock()
+			defer backend.mu.Unlock()
+			return backend.polled[full]
+		}, eventTimeout, 10*time.Millisecond, "polled once out of watches")
+
+		file := filepath.Join(full, "file.txt")
+		require.NoError(t, os.WriteFile(file, []byte("content"), 0o600))
+		assert.True(t, waitForChanEvent(t, watcher, file).Has(enum.EventTypeCreate))
+
+		// the rest is still watched with notifications
+		other := filepath.Join(dir, "other.txt")
+		require.NoError(t, os.WriteFile(other, []byte("content"), 0o600))
+		waitForChanEvent(t, watcher, other)
+		backend.poller.mu.Lock()
+		defer backend.poller.mu.Unlock()
+		assert.False(t, backend.poller.paths[dir])
+	})
+
+	t.Run("out of watches at start", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "full"), 0o750))
+		_, err := WatchRecursiveChan(dir, withLimitedBackend)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, syscall.ENOSPC)
+
+		watcher, err := WatchRecursiveChan(dir, WithPollingFallback(10*time.Millisecond), withLimitedBackend)
+		require.NoError(t, err, "polled from the start")
+		defer func() { _ = watcher.Close() }()
+		file := filepath.Join(dir, "full", "file.txt")
+		require.NoError(t, os.WriteFile(file, []byte("content"), 0o600))
+		waitForChanEvent(t, watcher, file)
+	})
+}
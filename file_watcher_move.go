@@ -0,0 +1,183 @@
+package fileutils
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+// moveWindow is how long a Rename event is held for the Move event of the new path, reporting it already
+const moveWindow = 50 * time.Millisecond
+
+// renamesPaired is set for platforms where fsnotify pairs the events of a rename itself, reporting
+// the old path with the Create event of the new one. Elsewhere moves can be guessed, see WithMoveGuessing.
+const renamesPaired = runtime.GOOS == "linux" || runtime.GOOS == "windows"
+
+// renamedFrom returns the old path of a Create event fsnotify paired with a rename, empty for other events.
+// fsnotify keeps it unexported, but renders it in Event.String as `CREATE "new" ← "old"`. This is a debug
+// format, not a part of the API, so fsnotify is pinned to v1.9.0 in go.mod, and TestRenamedFrom checks
+// the format with a real rename on linux and windows. Check it before upgrading fsnotify.
+func renamedFrom(event fsnotify.Event) string {
+	const sep = `" ← "` // can't be a part of a quoted name, as quotes inside it are escaped
+	s := event.String()
+	idx := strings.Index(s, sep)
+	if idx < 0 {
+		return ""
+	}
+	from, err := strconv.Unquote(s[idx+len(sep)-1:])
+	if err != nil {
+		return ""
+	}
+	return from
+}
+
+// renamePairer drops the Rename event of the old path of a Move event, holding Rename events for the Move to come.
+// Guessing, it also pairs a held Rename with a Create of a file with the same device, inode and size.
+// It is used by the watch loop only, so needs no locking.
+type renamePairer struct {
+	window time.Duration
+	held   []heldRename         // Rename events waiting for their Move, in arrival order
+	moved  map[string]time.Time // old paths of recent moves, to drop their Rename arriving after the Move
+	keys   map[string]fileKey   // last seen keys of watched paths, set if guessing
+}
+
+// heldRename is a Rename event waiting for the Move event of the new path
+type heldRename struct {
+	event FileEvent
+	at    time.Time
+	key   fileKey // last seen key of the old path, zero if not known
+}
+
+// newRenamePairer makes a pairer holding renames for the window, guessing moves if guess is set
+func newRenamePairer(window time.Duration, guess bool) *renamePairer {
+	res := &renamePairer{window: window, moved: map[string]time.Time{}}
+	if guess {
+		res.keys = map[string]fileKey{}
+	}
+	return res
+}
+
+// scan records the keys of path and, for a directory, its entries, all subdirectories too if recursive is set,
+// for guessing the moves of files not changed since
+func (p *renamePairer) scan(path string, recursive bool) {
+	if p.keys == nil {
+		return
+	}
+	_ = filepath.WalkDir(path, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil //nolint:nilerr // best-effort, the file is not paired then
+		}
+		p.record(name)
+		if d.IsDir() && name != path && !recursive {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// record keeps the current key of path if guessing, forgetting it for a removed path
+func (p *renamePairer) record(path string) {
+	if p.keys == nil {
+		return
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		delete(p.keys, path)
+		return
+	}
+	p.keys[path] = fileInfoKey(info)
+}
+
+// add takes an event arrived at now, returning the events to pass on now, none if the event is held or dropped
+func (p *renamePairer) add(event FileEvent, now time.Time) []FileEvent {
+	switch {
+	case event.Type == enum.EventTypeMove:
+		if !p.release(event.OldPath) {
+			p.moved[event.OldPath] = now // the Rename of the old path is still to come
+		}
+		if p.keys != nil {
+			delete(p.keys, event.OldPath)
+			p.record(event.Path)
+		}
+		return []FileEvent{event}
+
+	case event.Type == enum.EventTypeRename && len(event.Ops) <= 1:
+		if at, ok := p.moved[event.Path]; ok && now.Sub(at) < p.window {
+			delete(p.moved, event.Path)
+			return nil // reported by the Move already
+		}
+		p.held = append(p.held, heldRename{event: event, at: now, key: p.keys[event.Path]})
+		delete(p.keys, event.Path)
+		return nil
+
+	case event.Type == enum.EventTypeCreate && p.keys != nil:
+		p.record(event.Path)
+		if old, ok := p.guess(event.Path); ok {
+			return []FileEvent{{Path: event.Path, OldPath: old, Type: enum.EventTypeMove,
+				Ops: []enum.EventType{enum.EventTypeMove}}}
+		}
+		return []FileEvent{event}
+	}
+	p.record(event.Path)
+	return []FileEvent{event}
+}
+
+// guess removes and returns the path of the held Rename of the file created at path, found by its device,
+// inode and size, false if there is none. Renamed files not seen by the pairer before are not found.
+func (p *renamePairer) guess(path string) (string, bool) {
+	key, ok := p.keys[path]
+	if !ok || key.Ino == 0 {
+		return "", false
+	}
+	for i, h := range p.held {
+		if h.key.Ino == key.Ino && h.key.Dev == key.Dev && h.key.Size == key.Size {
+			p.held = append(p.held[:i], p.held[i+1:]...)
+			return h.event.Path, true
+		}
+	}
+	return "", false
+}
+
+// release drops the held Rename event of path, returning false if there is none
+func (p *renamePairer) release(path string) bool {
+	for i, h := range p.held {
+		if h.event.Path == path {
+			p.held = append(p.held[:i], p.held[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// due removes and returns Rename events not paired in the window at now, the files were moved out
+// of the watched paths or replaced another file
+func (p *renamePairer) due(now time.Time) []FileEvent {
+	for path, at := range p.moved {
+		if now.Sub(at) >= p.window {
+			delete(p.moved, path)
+		}
+	}
+
+	var res []FileEvent
+	for len(p.held) > 0 && now.Sub(p.held[0].at) >= p.window {
+		res = append(res, p.held[0].event)
+		p.held = p.held[1:]
+	}
+	return res
+}
+
+// next returns the time from now until the next held Rename is due, false if nothing is held
+func (p *renamePairer) next(now time.Time) (time.Duration, bool) {
+	if len(p.held) == 0 {
+		return 0, false
+	}
+	return p.held[0].at.Add(p.window).Sub(now), true
+}
@@ -0,0 +1,361 @@
+package fileutils
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+// defaultPollInterval is the interval of the poller if not set with WithPolling or WithPollingFallback
+const defaultPollInterval = time.Second
+
+// poller is the watchBackend polling watched paths, like fsnotify it watches files and direct entries
+// of directories. A file moved to another watched path is reported with a single event of its creation,
+// carrying the old path.
+type poller struct {
+	interval time.Duration
+	algo     enum.HashAlg // compare checksums of regular files too, if set
+	events   chan watchEvent
+	errs     chan error
+	done     chan struct{}
+
+	closeOnce sync.Once
+
+	mu    sync.Mutex           // protects paths and state, held for a whole poll
+	paths map[string]bool      // watched paths
+	state map[string]pollEntry // last seen watched paths and entries of watched directories
+}
+
+// pollEntry is the state of a path seen by the poller
+type pollEntry struct {
+	key  fileKey
+	mode os.FileMode
+	sum  string // checksum of a regular file, set if the poller compares checksums
+}
+
+// newPoller makes and starts a poller, polling every interval
+func newPoller(interval time.Duration, algo enum.HashAlg) *poller {
+	p := &poller{
+		interval: interval,
+		algo:     algo,
+		events:   make(chan watchEvent),
+		errs:     make(chan error),
+		done:     make(chan struct{}),
+		paths:    map[string]bool{},
+		state:    map[string]pollEntry{},
+	}
+	go p.run()
+	return p
+}
+
+// Add starts watching path, a file or directory
+func (p *poller) Add(path string) error {
+	if _, err := os.Lstat(path); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.paths[path] {
+		return nil
+	}
+	seen := map[string]pollEntry{}
+	if err := p.scan(path, seen); err != nil {
+		return err
+	}
+	p.paths[path] = true
+	for name, entry := range seen {
+		if _, ok := p.state[name]; !ok {
+			p.state[name] = entry
+		}
+	}
+	return nil
+}
+
+// Remove stops watching path
+func (p *poller) Remove(path string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.paths[path] {
+		return fmt.Errorf("can't remove non-existent watch: %s", path)
+	}
+	delete(p.paths, path)
+	for name := range p.state {
+		if !p.watched(name) {
+			delete(p.state, name)
+		}
+	}
+	return nil
+}
+
+// Close stops polling, it can be called more than once
+func (p *poller) Close() error {
+	p.closeOnce.Do(func() { close(p.done) })
+	return nil
+}
+
+// Events returns the channel of changes found by the poller
+func (p *poller) Events() <-chan watchEvent { return p.events }
+
+// Errors returns the channel of errors of the poller
+func (p *poller) Errors() <-chan error { return p.errs }
+
+// run polls every interval until the poller is closed
+func (p *poller) run() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+
+		events, errs := p.poll()
+		for _, event := range events {
+			select {
+			case p.events <- event:
+			case <-p.done:
+				return
+			}
+		}
+		for _, err := range errs {
+			select {
+			case p.errs <- err:
+			case <-p.done:
+				return
+			}
+		}
+	}
+}
+
+// poll scans the watched paths, returning the changes since the previous poll, changed paths first,
+// then moves, removed and created paths, all sorted by path
+func (p *poller) poll() (events []watchEvent, errs []error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cur := make(map[string]pollEntry, len(p.state))
+	var gone []string
+	for path := range p.paths {
+		err := p.scan(path, cur)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			gone = append(gone, path)
+		case err != nil:
+			errs = append(errs, err)
+		}
+	}
+
+	names := make([]string, 0, len(cur))
+	for name := range cur {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var created, removed []string
+	for _, name := range names {
+		prev, ok := p.state[name]
+		if !ok {
+			created = append(created, name)
+			continue
+		}
+		if op := p.changes(prev, cur[name]); op != 0 {
+			events = append(events, watchEvent{Event: fsnotify.Event{Name: name, Op: op}})
+		}
+	}
+	for name := range p.state {
+		if _, ok := cur[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+
+	// a removed path with the same inode, size and modification time as a created one was renamed,
+	// an inode of a removed file may be reused by a new one
+	renamed := map[string]bool{}
+	for _, oldName := range removed {
+		old := p.state[oldName]
+		for _, newName := range created {
+			if entry := cur[newName]; old.key.Ino != 0 && !renamed[newName] && entry.key == old.key {
+				events = append(events, watchEvent{Event: fsnotify.Event{Name: newName, Op: fsnotify.Create},
+					oldPath: oldName, moved: true})
+				renamed[oldName], renamed[newName] = true, true
+				break
+			}
+		}
+	}
+	for _, name := range removed {
+		if !renamed[name] {
+			events = append(events, watchEvent{Event: fsnotify.Event{Name: name, Op: fsnotify.Remove}})
+		}
+	}
+	for _, name := range created {
+		if !renamed[name] {
+			events = append(events, watchEvent{Event: fsnotify.Event{Name: name, Op: fsnotify.Create}})
+		}
+	}
+
+	p.state = cur
+	for _, path := range gone {
+		delete(p.paths, path) // like fsnotify, watches of removed paths are dropped
+	}
+	return events, errs
+}
+
+// changes returns the operations turning prev into cur, zero if the path is unchanged.
+// A path of another type or inode is a new one, directories change with their entries only.
+func (p *poller) changes(prev, cur pollEntry) fsnotify.Op {
+	if prev.mode.Type() != cur.mode.Type() || prev.key.Dev != cur.key.Dev || prev.key.Ino != cur.key.Ino {
+		return fsnotify.Create
+	}
+	var op fsnotify.Op
+	if !cur.mode.IsDir() && (prev.key.Size != cur.key.Size || prev.key.MTime != cur.key.MTime || prev.sum != cur.sum) {
+		op |= fsnotify.Write
+	}
+	if prev.mode.Perm() != cur.mode.Perm() {
+		op |= fsnotify.Chmod
+	}
+	return op
+}
+
+// scan adds path and, for a directory, its entries to seen
+func (p *poller) scan(path string, seen map[string]pollEntry) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	seen[path] = p.entry(path, info)
+	if !info.IsDir() {
+		return nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return fmt.Errorf("failed to read directory %s: %w", path, err)
+	}
+	for _, e := range entries {
+		name := filepath.Join(path, e.Name())
+		info, err := os.Lstat(name)
+		if err != nil {
+			continue // removed meanwhile
+		}
+		seen[name] = p.entry(name, info)
+	}
+	return nil
+}
+
+// entry makes the state of path described by info, with the checksum of a regular file if the poller compares them
+func (p *poller) entry(path string, info os.FileInfo) pollEntry {
+	res := pollEntry{key: fileInfoKey(info), mode: info.Mode()}
+	if p.algo != (enum.HashAlg{}) && info.Mode().IsRegular() {
+		res.sum, _ = Checksum(path, p.algo) // a file gone meanwhile has no checksum
+	}
+	return res
+}
+
+// watched checks if name is a watched path or an entry of a watched directory
+func (p *poller) watched(name string) bool {
+	return p.paths[name] || p.paths[filepath.Dir(name)]
+}
+
+// fallbackBackend is the watchBackend of WithPollingFallback once system notifications started,
+// watching paths with the poller when the system runs out of watches or open files for them
+type fallbackBackend struct {
+	watchBackend // system notifications
+	poller       *poller
+	events       chan watchEvent
+	errs         chan error
+	done         chan struct{}
+	closeOnce    sync.Once
+
+	mu     sync.Mutex
+	polled map[string]bool // paths watched by the poller
+}
+
+// newFallbackBackend makes a backend watching with notifications, falling back to the poller, and starts
+// passing on the events of both
+func newFallbackBackend(notifications watchBackend, p *poller) *fallbackBackend {
+	b := &fallbackBackend{watchBackend: notifications, poller: p, events: make(chan watchEvent),
+		errs: make(chan error), done: make(chan struct{}), polled: map[string]bool{}}
+	for _, src := range []watchBackend{notifications, p} {
+		go b.forward(src)
+	}
+	return b
+}
+
+// forward passes the events and errors of src on until the backend is closed
+func (b *fallbackBackend) forward(src watchBackend) {
+	for {
+		select {
+		case event, ok := <-src.Events():
+			if !ok {
+				return
+			}
+			select {
+			case b.events <- event:
+			case <-b.done:
+				return
+			}
+		case err, ok := <-src.Errors():
+			if !ok {
+				return
+			}
+			select {
+			case b.errs <- err:
+			case <-b.done:
+				return
+			}
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// Add starts watching path with notifications, or with the poller if there are no watches left
+func (b *fallbackBackend) Add(path string) error {
+	err := b.watchBackend.Add(path)
+	if err == nil || !isWatchLimitErr(err) {
+		return err
+	}
+	if err := b.poller.Add(path); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	b.polled[path] = true
+	b.mu.Unlock()
+	return nil
+}
+
+// Remove stops watching path
+func (b *fallbackBackend) Remove(path string) error {
+	b.mu.Lock()
+	polled := b.polled[path]
+	delete(b.polled, path)
+	b.mu.Unlock()
+	if polled {
+		return b.poller.Remove(path)
+	}
+	return b.watchBackend.Remove(path)
+}
+
+// Close stops both the notifications and the poller, it can be called more than once
+func (b *fallbackBackend) Close() error {
+	b.closeOnce.Do(func() { close(b.done) })
+	_ = b.poller.Close()
+	return b.watchBackend.Close()
+}
+
+// Events returns the channel of events of both the notifications and the poller
+func (b *fallbackBackend) Events() <-chan watchEvent { return b.events }
+
+// Errors returns the channel of errors of both the notifications and the poller
+func (b *fallbackBackend) Errors() <-chan error { return b.errs }
@@ -0,0 +1,155 @@
+package fileutils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+func TestPoller(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{"a.txt": "a", "b.txt": "b", "d.txt": "d", "sub/deep.txt": "deep"})
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	p := newPoller(time.Hour, enum.HashAlg{}) // polled by the test only
+	defer func() { _ = p.Close() }()
+	require.NoError(t, p.Add(dir))
+	events, errs := p.poll()
+	assert.Empty(t, events)
+	assert.Empty(t, errs)
+
+	require.NoError(t, os.WriteFile(path("a.txt"), []byte("changed"), 0o600))
+	require.NoError(t, os.Remove(path("b.txt")))
+	require.NoError(t, os.WriteFile(path("c.txt"), []byte("c"), 0o600))
+	require.NoError(t, os.Rename(path("d.txt"), path("e.txt")))
+	require.NoError(t, os.WriteFile(path("sub/deep.txt"), []byte("not watched"), 0o600))
+
+	events, errs = p.poll()
+	assert.Empty(t, errs)
+	event := func(name string, op fsnotify.Op) watchEvent {
+		return watchEvent{Event: fsnotify.Event{Name: path(name), Op: op}}
+	}
+	want := []watchEvent{event("a.txt", fsnotify.Write)}
+	if runtime.GOOS == "windows" { // no inodes to pair the rename
+		want = append(want, event("b.txt", fsnotify.Remove), event("d.txt", fsnotify.Remove),
+			event("c.txt", fsnotify.Create), event("e.txt", fsnotify.Create))
+	} else {
+		want = append(want, watchEvent{Event: fsnotify.Event{Name: path("e.txt"), Op: fsnotify.Create}, oldPath: path("d.txt"), moved: true},
+			event("b.txt", fsnotify.Remove), event("c.txt", fsnotify.Create))
+	}
+	assert.Equal(t, want, events)
+
+	events, _ = p.poll()
+	assert.Empty(t, events, "nothing changed since the previous poll")
+
+	if runtime.GOOS != "windows" {
+		require.NoError(t, os.Chmod(path("c.txt"), 0o640))
+		events, _ = p.poll()
+		assert.Equal(t, []watchEvent{event("c.txt", fsnotify.Chmod)}, events)
+	}
+
+	// watching the subdirectory too, the watched directory removed
+	require.NoError(t, p.Add(path("sub")))
+	require.NoError(t, p.Remove(dir))
+	require.Error(t, p.Remove(dir))
+	require.NoError(t, os.WriteFile(path("a.txt"), []byte("not watched any more"), 0o600))
+	require.NoError(t, os.RemoveAll(path("sub")))
+	events, _ = p.poll()
+	assert.Equal(t, []watchEvent{event("sub", fsnotify.Remove), event("sub/deep.txt", fsnotify.Remove)}, events)
+	assert.Empty(t, p.paths, "the watch of the removed directory is dropped")
+
+	require.Error(t, p.Add(path("missing")))
+}
+
+func TestPollerChecksum(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	require.NoError(t, os.WriteFile(file, []byte("content 1"), 0o600))
+	ts := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(file, ts, ts))
+
+	plain, summed := newPoller(time.Hour, enum.HashAlg{}), newPoller(time.Hour, enum.HashAlgSHA256)
+	defer func() { _, _ = plain.Close(), summed.Close() }()
+	require.NoError(t, plain.Add(dir))
+	require.NoError(t, summed.Add(dir))
+
+	// the same size and modification time
+	require.NoError(t, os.WriteFile(file, []byte("content 2"), 0o600))
+	require.NoError(t, os.Chtimes(file, ts, ts))
+
+	events, _ := plain.poll()
+	assert.Empty(t, events)
+	events, _ = summed.poll()
+	assert.Equal(t, []watchEvent{{Event: fsnotify.Event{Name: file, Op: fsnotify.Write}}}, events)
+}
+
+func TestFileWatcherPolling(t *testing.T) {
+	dir := t.TempDir()
+	watcher, err := NewFileWatcherChan(dir, WithPolling(10*time.Millisecond), WithMoves())
+	require.NoError(t, err)
+	defer func() { _ = watcher.Close() }()
+	_, ok := watcher.watcher.(*poller)
+	require.True(t, ok)
+
+	file := filepath.Join(dir, "file.txt")
+	require.NoError(t, os.WriteFile(file, []byte("content"), 0o600))
+	assert.Equal(t, enum.EventTypeCreate, waitForChanEvent(t, watcher, file).Type)
+
+	require.NoError(t, os.WriteFile(file, []byte("changed content"), 0o600))
+	assert.Equal(t, enum.EventTypeWrite, waitForChanEvent(t, watcher, file).Type)
+
+	if runtime.GOOS != "windows" {
+		moved := filepath.Join(dir, "moved.txt")
+		require.NoError(t, os.Rename(file, moved))
+		assert.Equal(t, FileEvent{Path: moved, OldPath: file, Type: enum.EventTypeMove,
+			Ops: []enum.EventType{enum.EventTypeMove}}, waitForChanEvent(t, watcher, moved))
+	}
+
+	t.Run("moves not paired by default", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("no inodes to find moves")
+		}
+		dir := t.TempDir()
+		file, moved := filepath.Join(dir, "file.txt"), filepath.Join(dir, "moved.txt")
+		require.NoError(t, os.WriteFile(file, []byte("content"), 0o600))
+		watcher, err := NewFileWatcherChan(dir, WithPolling(10*time.Millisecond))
+		require.NoError(t, err)
+		defer func() { _ = watcher.Close() }()
+
+		require.NoError(t, os.Rename(file, moved))
+		assert.Equal(t, FileEvent{Path: file, Type: enum.EventTypeRename, Ops: []enum.EventType{enum.EventTypeRename}},
+			waitForChanEvent(t, watcher, file))
+		assert.Equal(t, FileEvent{Path: moved, Type: enum.EventTypeCreate, Ops: []enum.EventType{enum.EventTypeCreate}},
+			waitForChanEvent(t, watcher, moved))
+	})
+
+	t.Run("recursive", func(t *testing.T) {
+		dir := t.TempDir()
+		watcher, err := WatchRecursiveChan(dir, WithPolling(10*time.Millisecond))
+		require.NoError(t, err)
+		defer func() { _ = watcher.Close() }()
+
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "new", "nested"), 0o750))
+		waitForChanEvent(t, watcher, filepath.Join(dir, "new", "nested"))
+		file := filepath.Join(dir, "new", "nested", "file.txt")
+		require.NoError(t, os.WriteFile(file, []byte("content"), 0o600))
+		assert.True(t, waitForChanEvent(t, watcher, file).Has(enum.EventTypeCreate))
+	})
+
+	t.Run("fallback not needed", func(t *testing.T) {
+		watcher, err := NewFileWatcherChan(dir, WithPollingFallback(10*time.Millisecond))
+		require.NoError(t, err)
+		defer func() { _ = watcher.Close() }()
+		backend, ok := watcher.watcher.(*fallbackBackend)
+		require.True(t, ok, "system notifications work here")
+		assert.Empty(t, backend.polled)
+	})
+}
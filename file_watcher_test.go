@@ -1,13 +1,23 @@
 package fileutils
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/go-pkgz/fileutils/enum"
 )
 
 // eventTimeout bounds how long a test waits for an event it expects to arrive.
@@ -85,6 +95,58 @@ func TestWatchRecursive(t *testing.T) {
 	waitForEvent(t, eventCh, testFile)
 }
 
+func TestWatchRecursiveNewDirs(t *testing.T) {
+	tmpDir := t.TempDir()
+	watcher, err := WatchRecursiveChan(tmpDir, WithBuffer(1000, enum.DeliveryPolicyBlock))
+	require.NoError(t, err)
+	defer func() { _ = watcher.Close() }()
+
+	// the nested directory and the file are likely created before the new directory is watched
+	newDir := filepath.Join(tmpDir, "new")
+	require.NoError(t, os.MkdirAll(filepath.Join(newDir, "nested"), 0o750))
+	early := filepath.Join(newDir, "nested", "early.txt")
+	require.NoError(t, os.WriteFile(early, []byte("early"), 0o600))
+	assert.True(t, waitForChanEvent(t, watcher, early).Has(enum.EventTypeCreate))
+
+	// the nested directory is watched by now
+	late := filepath.Join(newDir, "nested", "late.txt")
+	require.NoError(t, os.WriteFile(late, []byte("late"), 0o600))
+	waitForChanEvent(t, watcher, late)
+
+	hasDir := func(dir string) bool {
+		watcher.dirsMu.Lock()
+		defer watcher.dirsMu.Unlock()
+		return watcher.dirs[dir]
+	}
+	assert.True(t, hasDir(tmpDir))
+	assert.True(t, hasDir(newDir))
+	assert.True(t, hasDir(filepath.Join(newDir, "nested")))
+
+	require.NoError(t, os.RemoveAll(newDir))
+	waitForChanEvent(t, watcher, newDir)
+	assert.Eventually(t, func() bool { return !hasDir(newDir) && !hasDir(filepath.Join(newDir, "nested")) },
+		eventTimeout, 10*time.Millisecond)
+	assert.True(t, hasDir(tmpDir))
+}
+
+func TestWatchRecursiveRenamedDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	oldDir := filepath.Join(tmpDir, "old")
+	require.NoError(t, os.MkdirAll(filepath.Join(oldDir, "nested", "deeper"), 0o750))
+	watcher, err := WatchRecursiveChan(tmpDir, WithBuffer(1000, enum.DeliveryPolicyBlock))
+	require.NoError(t, err)
+	defer func() { _ = watcher.Close() }()
+
+	// the subdirectories are reported once they are watched under the new path
+	newDir := filepath.Join(tmpDir, "new")
+	require.NoError(t, os.Rename(oldDir, newDir))
+	waitForChanEvent(t, watcher, filepath.Join(newDir, "nested", "deeper"))
+
+	path := filepath.Join(newDir, "nested", "deeper", "file.txt")
+	require.NoError(t, os.WriteFile(path, []byte("content"), 0o600))
+	waitForChanEvent(t, watcher, path)
+}
+
 func TestFileWatcherAddPath(t *testing.T) {
 	tmpDir := t.TempDir()
 	testFile1 := filepath.Join(tmpDir, "test1.txt")
@@ -127,3 +189,508 @@ func TestFileWatcherRemovePath(t *testing.T) {
 		assert.NotEqual(t, testFile2, event.Path, "removed path still reports events")
 	}
 }
+
+func TestFileWatcherErrorHandler(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte("content"), 0o600))
+
+	var errs []error
+	watcher, err := NewFileWatcher(testFile, func(FileEvent) {}, WithErrorHandler(func(err error) { errs = append(errs, err) }))
+	require.NoError(t, err)
+	defer func() { _ = watcher.Close() }()
+
+	// the kernel queue can't be overflowed reliably in a test, errors are passed the way watch does it
+	watcher.handleError(fsnotify.ErrEventOverflow)
+	watcher.handleError(errors.New("other error"))
+	require.Len(t, errs, 2)
+	assert.True(t, errors.Is(errs[0], ErrEventOverflow))
+	assert.True(t, errors.Is(errs[0], fsnotify.ErrEventOverflow))
+	assert.False(t, errors.Is(errs[1], ErrEventOverflow))
+	assert.Equal(t, "other error", errs[1].Error())
+
+	t.Run("logged by default", func(t *testing.T) {
+		var buf bytes.Buffer
+		log.SetOutput(&buf)
+		defer log.SetOutput(os.Stderr)
+
+		watcher, err := WatchRecursive(filepath.Dir(testFile), func(FileEvent) {})
+		require.NoError(t, err)
+		defer func() { _ = watcher.Close() }()
+		watcher.handleError(fsnotify.ErrEventOverflow)
+		assert.Contains(t, buf.String(), "[WARN] file watcher error: file watcher event queue overflow")
+	})
+}
+
+// waitForChanEvent reads events of a channel based watcher until one for path arrives
+func waitForChanEvent(t *testing.T, watcher *FileWatcher, path string) FileEvent {
+	t.Helper()
+	deadline := time.After(eventTimeout)
+	for {
+		select {
+		case event, ok := <-watcher.Events():
+			require.True(t, ok, "events channel closed")
+			if event.Path == path {
+				return event
+			}
+		case err := <-watcher.Errors():
+			t.Fatalf("unexpected error: %v", err)
+		case <-deadline:
+			t.Fatalf("timeout waiting for an event on %s", path)
+		}
+	}
+}
+
+// waitForClosed waits for both channels of a channel based watcher to be closed
+func waitForClosed(t *testing.T, watcher *FileWatcher) {
+	t.Helper()
+	deadline := time.After(eventTimeout)
+	events, errs := watcher.Events(), watcher.Errors()
+	for events != nil || errs != nil {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				events = nil
+			}
+		case _, ok := <-errs:
+			if !ok {
+				errs = nil
+			}
+		case <-deadline:
+			t.Fatal("timeout waiting for the channels to be closed")
+		}
+	}
+}
+
+func TestFileWatcherChan(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte("initial content"), 0o600))
+
+	watcher, err := NewFileWatcherChan(testFile)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(testFile, []byte("modified content"), 0o600))
+	waitForChanEvent(t, watcher, testFile)
+
+	require.NoError(t, watcher.Close())
+	require.NoError(t, watcher.Close(), "closing twice is fine")
+	waitForClosed(t, watcher)
+
+	t.Run("recursive", func(t *testing.T) {
+		subDir := filepath.Join(tmpDir, "sub")
+		require.NoError(t, os.Mkdir(subDir, 0o750))
+		watcher, err := WatchRecursiveChan(tmpDir, WithBuffer(0, enum.DeliveryPolicyBlock))
+		require.NoError(t, err)
+		defer func() { _ = watcher.Close() }()
+
+		subFile := filepath.Join(subDir, "test.txt")
+		require.NoError(t, os.WriteFile(subFile, []byte("content"), 0o600))
+		waitForChanEvent(t, watcher, subFile)
+	})
+
+	t.Run("blocked watcher closes", func(t *testing.T) {
+		watcher, err := WatchRecursiveChan(tmpDir, WithBuffer(0, enum.DeliveryPolicyBlock))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(testFile, []byte("nobody reads this"), 0o600))
+		time.Sleep(50 * time.Millisecond) // let the watcher block on sending
+		require.NoError(t, watcher.Close())
+		waitForClosed(t, watcher)
+	})
+
+	t.Run("drop policy", func(t *testing.T) {
+		dir := t.TempDir()
+		watcher, err := NewFileWatcherChan(dir, WithBuffer(1, enum.DeliveryPolicyDrop))
+		require.NoError(t, err)
+		defer func() { _ = watcher.Close() }()
+
+		for i := 0; i < 10; i++ {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.txt", i)), []byte("content"), 0o600))
+		}
+		select {
+		case err := <-watcher.Errors():
+			assert.True(t, errors.Is(err, ErrEventDropped), err)
+		case <-time.After(eventTimeout):
+			t.Fatal("timeout waiting for a dropped event error")
+		}
+	})
+
+	t.Run("callback watcher has no channels", func(t *testing.T) {
+		watcher, _ := newTestWatcher(t, testFile)
+		assert.Nil(t, watcher.Events())
+		assert.Nil(t, watcher.Errors())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := NewFileWatcherChan("")
+		require.Error(t, err)
+		_, err = NewFileWatcherChan(filepath.Join(tmpDir, "missing"))
+		require.Error(t, err)
+		_, err = WatchRecursiveChan("")
+		require.Error(t, err)
+		_, err = WatchRecursiveChan(testFile)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "directory does not exist")
+	})
+}
+
+func TestFileWatcherRun(t *testing.T) {
+	tmpDir := t.TempDir()
+	watcher, err := WatchRecursiveChan(tmpDir)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- watcher.Run(ctx) }()
+
+	testFile := filepath.Join(tmpDir, "test.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte("content"), 0o600))
+	waitForChanEvent(t, watcher, testFile)
+
+	cancel()
+	select {
+	case err := <-runErr:
+		assert.True(t, errors.Is(err, context.Canceled))
+	case <-time.After(eventTimeout):
+		t.Fatal("Run didn't return on cancellation")
+	}
+	waitForClosed(t, watcher)
+
+	// a closed watcher doesn't block Run
+	assert.NoError(t, watcher.Run(context.Background()))
+
+	t.Run("callback watcher", func(t *testing.T) {
+		watcher, _ := newTestWatcher(t, tmpDir)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.True(t, errors.Is(watcher.Run(ctx), context.DeadlineExceeded))
+		require.NoError(t, watcher.Close())
+	})
+}
+
+func TestEventDebouncer(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	event := func(path string, typ enum.EventType) FileEvent {
+		return FileEvent{Path: path, Type: typ, Ops: []enum.EventType{typ}}
+	}
+
+	d := newEventDebouncer(100*time.Millisecond, false)
+	_, ok := d.next(at(0))
+	assert.False(t, ok)
+
+	d.add(event("a", enum.EventTypeCreate), at(0))
+	d.add(event("b", enum.EventTypeWrite), at(10))
+	d.add(event("a", enum.EventTypeChmod), at(50))
+	d.add(event("a", enum.EventTypeWrite), at(60))
+	wait, ok := d.next(at(60))
+	require.True(t, ok)
+	assert.Equal(t, 50*time.Millisecond, wait, "b is due first")
+
+	assert.Empty(t, d.due(at(100)))
+	assert.Equal(t, []FileEvent{event("b", enum.EventTypeWrite)}, d.due(at(110)))
+	assert.Equal(t, []FileEvent{{Path: "a", Type: enum.EventTypeCreate,
+		Ops: []enum.EventType{enum.EventTypeCreate, enum.EventTypeWrite, enum.EventTypeChmod}}}, d.due(at(160)))
+	_, ok = d.next(at(160))
+	assert.False(t, ok)
+
+	// removal and recreation change the type, other events don't
+	d.add(event("c", enum.EventTypeWrite), at(200))
+	d.add(event("c", enum.EventTypeRename), at(210))
+	d.add(event("d", enum.EventTypeRemove), at(220))
+	d.add(event("d", enum.EventTypeCreate), at(230))
+	d.add(event("d", enum.EventTypeChmod), at(240))
+	assert.Equal(t, []FileEvent{
+		{Path: "c", Type: enum.EventTypeRename, Ops: []enum.EventType{enum.EventTypeWrite, enum.EventTypeRename}},
+		{Path: "d", Type: enum.EventTypeCreate, Ops: []enum.EventType{enum.EventTypeCreate, enum.EventTypeRemove, enum.EventTypeChmod}},
+	}, d.due(at(400)))
+
+	t.Run("batch", func(t *testing.T) {
+		d := newEventDebouncer(100*time.Millisecond, true)
+		d.add(event("b", enum.EventTypeWrite), at(0))
+		d.add(event("a", enum.EventTypeWrite), at(90))
+		wait, ok := d.next(at(90))
+		require.True(t, ok)
+		assert.Equal(t, 100*time.Millisecond, wait)
+		assert.Empty(t, d.due(at(150)), "b waits for the whole watcher to be quiet")
+		assert.Equal(t, []FileEvent{event("b", enum.EventTypeWrite), event("a", enum.EventTypeWrite)}, d.due(at(190)))
+	})
+
+	t.Run("max wait", func(t *testing.T) {
+		d := newEventDebouncer(100*time.Millisecond, false)
+		for ms := 0; ms < 1000; ms += 50 {
+			d.add(event("a", enum.EventTypeWrite), at(ms))
+		}
+		d.add(event("b", enum.EventTypeWrite), at(950))
+		wait, ok := d.next(at(950))
+		require.True(t, ok)
+		assert.Equal(t, 50*time.Millisecond, wait, "a is due after ten windows")
+		assert.Equal(t, []FileEvent{event("a", enum.EventTypeWrite)}, d.due(at(1000)), "a changing all the time")
+
+		d = newEventDebouncer(100*time.Millisecond, true)
+		for ms := 0; ms < 1000; ms += 50 {
+			d.add(event(strconv.Itoa(ms), enum.EventTypeWrite), at(ms))
+		}
+		wait, ok = d.next(at(950))
+		require.True(t, ok)
+		assert.Equal(t, 50*time.Millisecond, wait)
+		assert.Len(t, d.due(at(1000)), 20, "batch of a watcher never quiet")
+	})
+
+	t.Run("full batch", func(t *testing.T) {
+		d := newEventDebouncer(100*time.Millisecond, true)
+		for i := 0; i < maxBatchEvents; i++ {
+			d.add(event(strconv.Itoa(i), enum.EventTypeCreate), at(0))
+		}
+		wait, ok := d.next(at(0))
+		require.True(t, ok)
+		assert.Equal(t, time.Duration(0), wait)
+		assert.Len(t, d.due(at(0)), maxBatchEvents)
+		_, ok = d.next(at(0))
+		assert.False(t, ok)
+	})
+}
+
+func TestFileWatcherDebounce(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.txt")
+	require.NoError(t, os.WriteFile(testFile, []byte("initial content"), 0o600))
+
+	eventCh := make(chan FileEvent, 100)
+	watcher, err := NewFileWatcher(tmpDir, func(event FileEvent) { eventCh <- event }, WithDebounce(200*time.Millisecond))
+	require.NoError(t, err)
+	defer func() { _ = watcher.Close() }()
+
+	require.NoError(t, os.WriteFile(testFile, []byte("modified content"), 0o600))
+	require.NoError(t, os.Chmod(testFile, 0o640))
+	require.NoError(t, os.WriteFile(testFile, []byte("modified again"), 0o600))
+
+	skipped := waitForEvent(t, eventCh, testFile)
+	assert.Empty(t, skipped)
+	select {
+	case event := <-eventCh:
+		t.Fatalf("unexpected second event %+v", event)
+	case <-time.After(400 * time.Millisecond):
+	}
+
+	t.Run("batch", func(t *testing.T) {
+		batches := make(chan []FileEvent, 10)
+		watcher, err := NewFileWatcherChan(tmpDir, WithBatch(func(events []FileEvent) { batches <- events }))
+		require.NoError(t, err)
+		defer func() { _ = watcher.Close() }()
+
+		file1, file2 := filepath.Join(tmpDir, "batch1.txt"), filepath.Join(tmpDir, "batch2.txt")
+		require.NoError(t, os.WriteFile(file1, []byte("content"), 0o600))
+		require.NoError(t, os.WriteFile(file2, []byte("content"), 0o600))
+
+		select {
+		case events := <-batches:
+			require.Len(t, events, 2)
+			assert.Equal(t, file1, events[0].Path)
+			assert.Equal(t, enum.EventTypeCreate, events[0].Type)
+			assert.Equal(t, file2, events[1].Path)
+		case <-time.After(eventTimeout):
+			t.Fatal("timeout waiting for a batch")
+		}
+	})
+}
+
+func TestNewFileEvent(t *testing.T) {
+	tbl := []struct {
+		op      fsnotify.Op
+		wantOK  bool
+		wantTyp enum.EventType
+		wantOps []enum.EventType
+	}{
+		{fsnotify.Write, true, enum.EventTypeWrite, []enum.EventType{enum.EventTypeWrite}},
+		{fsnotify.Create | fsnotify.Write, true, enum.EventTypeCreate, []enum.EventType{enum.EventTypeCreate, enum.EventTypeWrite}},
+		{fsnotify.Chmod | fsnotify.Write, true, enum.EventTypeWrite, []enum.EventType{enum.EventTypeWrite, enum.EventTypeChmod}},
+		{fsnotify.Rename | fsnotify.Remove, true, enum.EventTypeRemove, []enum.EventType{enum.EventTypeRemove, enum.EventTypeRename}},
+		{0, false, enum.EventType{}, nil},
+	}
+	for _, tt := range tbl {
+		event, ok := newFileEvent(watchEvent{Event: fsnotify.Event{Name: "/some/path", Op: tt.op}})
+		assert.Equal(t, tt.wantOK, ok, tt.op.String())
+		assert.Equal(t, tt.wantTyp, event.Type, tt.op.String())
+		assert.Equal(t, tt.wantOps, event.Ops, tt.op.String())
+		assert.Equal(t, "/some/path", event.Path)
+	}
+
+	event, _ := newFileEvent(watchEvent{Event: fsnotify.Event{Name: "file", Op: fsnotify.Create | fsnotify.Chmod}})
+	assert.True(t, event.Has(enum.EventTypeCreate))
+	assert.True(t, event.Has(enum.EventTypeChmod))
+	assert.False(t, event.Has(enum.EventTypeWrite))
+
+	// events made without Ops still answer for their type
+	assert.True(t, FileEvent{Path: "file", Type: enum.EventTypeWrite}.Has(enum.EventTypeWrite))
+	assert.False(t, FileEvent{Path: "file", Type: enum.EventTypeWrite}.Has(enum.EventTypeCreate))
+
+	assert.Empty(t, renamedFrom(fsnotify.Event{Name: "file", Op: fsnotify.Create}), "not paired with a rename")
+
+	event, _ = newFileEvent(watchEvent{Event: fsnotify.Event{Name: "new", Op: fsnotify.Create}, oldPath: "old"})
+	assert.Equal(t, FileEvent{Path: "new", OldPath: "old", Type: enum.EventTypeMove,
+		Ops: []enum.EventType{enum.EventTypeMove}}, event)
+}
+
+func TestRenamePairer(t *testing.T) {
+	start := time.Now()
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	rename := func(path string) FileEvent {
+		return FileEvent{Path: path, Type: enum.EventTypeRename, Ops: []enum.EventType{enum.EventTypeRename}}
+	}
+	move := func(path, from string) FileEvent {
+		return FileEvent{Path: path, OldPath: from, Type: enum.EventTypeMove, Ops: []enum.EventType{enum.EventTypeMove}}
+	}
+
+	t.Run("paired by the system", func(t *testing.T) {
+		p := newRenamePairer(50*time.Millisecond, false)
+		assert.Empty(t, p.add(rename("a"), at(0)))
+		wait, ok := p.next(at(10))
+		assert.True(t, ok)
+		assert.Equal(t, 40*time.Millisecond, wait)
+		assert.Equal(t, []FileEvent{move("b", "a")}, p.add(move("b", "a"), at(10)))
+		_, ok = p.next(at(10))
+		assert.False(t, ok, "the rename is reported by the move")
+
+		// the rename of the old path may come after the move
+		assert.Equal(t, []FileEvent{move("d", "c")}, p.add(move("d", "c"), at(20)))
+		assert.Empty(t, p.add(rename("c"), at(25)))
+
+		// a rename with no move, out of the watched paths
+		assert.Empty(t, p.add(rename("e"), at(30)))
+		create := FileEvent{Path: "f", Type: enum.EventTypeCreate, Ops: []enum.EventType{enum.EventTypeCreate}}
+		assert.Equal(t, []FileEvent{create}, p.add(create, at(40)), "not paired with the rename")
+		assert.Empty(t, p.due(at(79)))
+		assert.Equal(t, []FileEvent{rename("e")}, p.due(at(80)))
+		assert.Empty(t, p.moved)
+	})
+
+	t.Run("guessed", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("no inodes to guess moves by")
+		}
+		dir := t.TempDir()
+		writeTestTree(t, dir, map[string]string{"a.txt": "file a", "b.txt": "file b"})
+		path := func(name string) string { return filepath.Join(dir, name) }
+		create := func(name string) FileEvent {
+			return FileEvent{Path: path(name), Type: enum.EventTypeCreate, Ops: []enum.EventType{enum.EventTypeCreate}}
+		}
+
+		p := newRenamePairer(50*time.Millisecond, true)
+		p.scan(dir, false)
+		require.NoError(t, os.Rename(path("a.txt"), path("moved.txt")))
+		require.NoError(t, os.WriteFile(path("new.txt"), []byte("file a"), 0o600))
+		assert.Empty(t, p.add(rename(path("a.txt")), at(0)))
+		assert.Equal(t, []FileEvent{create("new.txt")}, p.add(create("new.txt"), at(5)), "another file of the same size")
+		assert.Equal(t, []FileEvent{move(path("moved.txt"), path("a.txt"))}, p.add(create("moved.txt"), at(10)))
+
+		// moved out of the watched paths, and a file not seen before moved in
+		require.NoError(t, os.Rename(path("b.txt"), filepath.Join(t.TempDir(), "b.txt")))
+		assert.Empty(t, p.add(rename(path("b.txt")), at(20)))
+		assert.Equal(t, []FileEvent{create("new.txt")}, p.add(create("new.txt"), at(25)))
+		assert.Equal(t, []FileEvent{rename(path("b.txt"))}, p.due(at(70)))
+
+		// a moved file is seen under its new path
+		require.NoError(t, os.Rename(path("moved.txt"), path("again.txt")))
+		assert.Empty(t, p.add(rename(path("moved.txt")), at(100)))
+		assert.Equal(t, []FileEvent{move(path("again.txt"), path("moved.txt"))}, p.add(create("again.txt"), at(105)))
+		_, ok := p.next(at(105))
+		assert.False(t, ok)
+	})
+}
+
+func TestRenamedFrom(t *testing.T) {
+	if !renamesPaired {
+		t.Skip("renames are not paired on this platform")
+	}
+	// the old path is taken from the debug format of fsnotify events, pinned here with a real rename
+	tmpDir := t.TempDir()
+	oldName := `old "name" ← with arrow.txt`
+	if runtime.GOOS == "windows" {
+		oldName = "old name ← with arrow.txt" // no quotes allowed
+	}
+	oldPath, newPath := filepath.Join(tmpDir, oldName), filepath.Join(tmpDir, "new.txt")
+	require.NoError(t, os.WriteFile(oldPath, []byte("content"), 0o600))
+
+	watcher, err := fsnotify.NewWatcher()
+	require.NoError(t, err)
+	defer func() { _ = watcher.Close() }()
+	require.NoError(t, watcher.Add(tmpDir))
+
+	require.NoError(t, os.Rename(oldPath, newPath))
+	for {
+		select {
+		case event := <-watcher.Events:
+			if event.Name == newPath && event.Has(fsnotify.Create) {
+				assert.Equal(t, oldPath, renamedFrom(event))
+				return
+			}
+			assert.Empty(t, renamedFrom(event), "%v", event)
+		case err := <-watcher.Errors:
+			require.NoError(t, err)
+		case <-time.After(eventTimeout):
+			t.Fatal("timeout waiting for the create event")
+		}
+	}
+}
+
+func TestFileWatcherMoveGuessing(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no inodes to guess moves by")
+	}
+	// paired by the system on linux, guessed on macOS and BSD
+	tmpDir := t.TempDir()
+	oldPath, newPath := filepath.Join(tmpDir, "old.txt"), filepath.Join(tmpDir, "new.txt")
+	require.NoError(t, os.WriteFile(oldPath, []byte("content"), 0o600))
+	watcher, err := NewFileWatcherChan(tmpDir, WithMoveGuessing())
+	require.NoError(t, err)
+	defer func() { _ = watcher.Close() }()
+
+	require.NoError(t, os.Rename(oldPath, newPath))
+	assert.Equal(t, FileEvent{Path: newPath, OldPath: oldPath, Type: enum.EventTypeMove,
+		Ops: []enum.EventType{enum.EventTypeMove}}, waitForChanEvent(t, watcher, newPath))
+}
+
+func TestFileWatcherMove(t *testing.T) {
+	if !renamesPaired {
+		t.Skip("moves are not known on this platform")
+	}
+	tmpDir := t.TempDir()
+	oldPath, newPath := filepath.Join(tmpDir, "old.txt"), filepath.Join(tmpDir, "new.txt")
+	require.NoError(t, os.WriteFile(oldPath, []byte("content"), 0o600))
+
+	t.Run("not paired by default", func(t *testing.T) {
+		watcher, err := NewFileWatcherChan(tmpDir)
+		require.NoError(t, err)
+		defer func() { _ = watcher.Close() }()
+
+		require.NoError(t, os.Rename(oldPath, newPath))
+		event := waitForChanEvent(t, watcher, newPath)
+		assert.Equal(t, FileEvent{Path: newPath, Type: enum.EventTypeCreate, Ops: []enum.EventType{enum.EventTypeCreate}}, event)
+		require.NoError(t, os.Rename(newPath, oldPath))
+		assert.True(t, waitForChanEvent(t, watcher, newPath).Has(enum.EventTypeRename))
+	})
+
+	watcher, err := NewFileWatcherChan(tmpDir, WithMoves())
+	require.NoError(t, err)
+	defer func() { _ = watcher.Close() }()
+
+	require.NoError(t, os.Rename(oldPath, newPath))
+	event := waitForChanEvent(t, watcher, newPath)
+	assert.Equal(t, FileEvent{Path: newPath, OldPath: oldPath, Type: enum.EventTypeMove,
+		Ops: []enum.EventType{enum.EventTypeMove}}, event)
+
+	// the rename of the old path is not reported separately
+	marker := filepath.Join(tmpDir, "marker.txt")
+	time.Sleep(2 * moveWindow)
+	require.NoError(t, os.WriteFile(marker, []byte("marker"), 0o600))
+	for {
+		select {
+		case event := <-watcher.Events():
+			if event.Path == marker {
+				return
+			}
+			assert.NotEqual(t, oldPath, event.Path, "unexpected %v", event)
+		case <-time.After(eventTimeout):
+			t.Fatal("timeout waiting for the marker event")
+		}
+	}
+}
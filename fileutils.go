@@ -13,6 +13,10 @@ import (
 	"errors"
 	"fmt"
 	"hash"
+	"hash/adler32"
+	"hash/crc32"
+	"hash/crc64"
+	"hash/fnv"
 	"io"
 	"os"
 	"path/filepath"
@@ -40,6 +44,14 @@ const (
 	hashAlgSHA512
 	hashAlgSHA512_224
 	hashAlgSHA512_256
+	hashAlgCRC32
+	hashAlgCRC32C
+	hashAlgCRC64ISO
+	hashAlgCRC64ECMA
+	hashAlgAdler32
+	hashAlgFNV32A
+	hashAlgFNV64A
+	hashAlgFNV128A
 )
 
 // IsFile returns true if filename exists
@@ -225,7 +237,7 @@ func SanitizePath(s string) string {
 	s = strings.ReplaceAll(s, `\`, "/")
 
 	if len(s) > maxPathLength {
-		s = s[:maxPathLength]
+		s = truncateUTF8(s, maxPathLength)
 	}
 
 	return s
@@ -301,76 +313,170 @@ func moveFile(src, dst string, rename func(oldpath, newpath string) error) error
 // TouchFile creates an empty file if it doesn't exist,
 // or updates access and modification times if it does.
 func TouchFile(path string) error {
+	return TouchFileWith(path, TouchOptions{CreateDirs: true})
+}
+
+// TouchOptions controls TouchFileWith, the zero value works like touch(1) without flags
+type TouchOptions struct {
+	AccessTime    time.Time   // access time to set, the current time if zero, like touch -d
+	ModTime       time.Time   // modification time to set, the current time if zero, like touch -d
+	Reference     string      // file to take the times from instead of the current time, like touch -r
+	OnlyAccess    bool        // change the access time only, like touch -a
+	OnlyMod       bool        // change the modification time only, like touch -m
+	NoCreate      bool        // don't create a missing file, like touch -c
+	Mode          os.FileMode // mode of a created file, 0o644 if zero
+	CreateDirs    bool        // create missing parent directories of a created file
+	NoDereference bool        // change the times of a symlink itself, like touch -h, not supported on windows
+}
+
+// TouchFileWith creates an empty file if it doesn't exist, unless opts.NoCreate is set,
+// and sets its access and modification times as opts tells. Times given explicitly win over
+// the times of opts.Reference. Setting both OnlyAccess and OnlyMod changes both times, as touch does.
+func TouchFileWith(path string, opts TouchOptions) error {
 	if path == "" {
 		return errors.New("empty path")
 	}
 
-	// try to get file info
-	_, err := os.Stat(path)
+	now := time.Now()
+	atime, mtime := now, now
+	if opts.Reference != "" {
+		var err error
+		if atime, mtime, err = fileTimes(opts.Reference, false); err != nil {
+			return fmt.Errorf("failed to get times of reference file: %w", err)
+		}
+	}
+	if !opts.AccessTime.IsZero() {
+		atime = opts.AccessTime
+	}
+	if !opts.ModTime.IsZero() {
+		mtime = opts.ModTime
+	}
+
+	curAtime, curMtime, err := fileTimes(path, opts.NoDereference)
 	if err != nil {
 		if !os.IsNotExist(err) {
 			return fmt.Errorf("failed to stat file: %w", err)
 		}
-		// create empty file with default mode
-		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
-			return fmt.Errorf("failed to create directory: %w", err)
+		if opts.NoCreate {
+			return nil
 		}
-		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644) //nolint:gosec // intentionally permissive
-		if err != nil {
-			return fmt.Errorf("failed to create file: %w", err)
+		if err = createEmptyFile(path, opts.Mode, opts.CreateDirs); err != nil {
+			return err
 		}
-		if err := f.Close(); err != nil {
-			return fmt.Errorf("failed to close file: %w", err)
+		if opts.Reference == "" && opts.AccessTime.IsZero() && opts.ModTime.IsZero() {
+			return nil // the times of a file just created are current already
 		}
-		return nil
+		curAtime, curMtime = now, now
 	}
 
-	// file exists, update timestamps
-	now := time.Now()
-	return os.Chtimes(path, now, now)
+	// the times not asked for are kept, with neither asked for both are
+	if opts.OnlyMod && !opts.OnlyAccess {
+		atime = curAtime
+	}
+	if opts.OnlyAccess && !opts.OnlyMod {
+		mtime = curMtime
+	}
+
+	if err = setFileTimes(path, atime, mtime, opts.NoDereference); err != nil {
+		return fmt.Errorf("failed to set file times: %w", err)
+	}
+	return nil
+}
+
+// createEmptyFile creates an empty file with the given mode, 0o644 if zero
+func createEmptyFile(path string, mode os.FileMode, createDirs bool) error {
+	if mode == 0 {
+		mode = 0o644
+	}
+	if createDirs {
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+	}
+	// no O_TRUNC, a file created by someone else in the meantime is left as is, like touch does
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, mode) //nolint:gosec // mode is up to the caller
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+	return nil
 }
 
 // Checksum calculates the checksum of a file using the specified hash algorithm.
-// Supported algorithms are MD5, SHA1, SHA224, SHA256, SHA384, SHA512, SHA512_224, and SHA512_256.
-func Checksum(path string, algo enum.HashAlg) (string, error) {
+// Supported algorithms are MD5, SHA1, SHA224, SHA256, SHA384, SHA512, SHA512_224, and SHA512_256,
+// as well as the non-cryptographic CRC32 (IEEE), CRC32C (Castagnoli), CRC64ISO, CRC64ECMA, Adler32,
+// FNV32A, FNV64A and FNV128A (FNV-1a). Checksums of the latter are hex encoded in big-endian order.
+// Other algorithms can be added with RegisterHash and used with ChecksumByName.
+// With WithChecksumCache the checksum of an unchanged file is taken from the cache.
+func Checksum(path string, algo enum.HashAlg, opts ...ChecksumOption) (string, error) {
 	if path == "" {
 		return "", errors.New("empty path")
 	}
+	var o checksumOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.cache != nil {
+		return o.cache.Checksum(path, algo)
+	}
+
+	h, err := newHash(algo)
+	if err != nil {
+		return "", err
+	}
+	return checksumFile(path, h)
+}
 
-	var h hash.Hash
+// newHash returns a new hash.Hash for the algorithm
+func newHash(algo enum.HashAlg) (hash.Hash, error) {
 	switch algo {
 	case enum.HashAlgMD5:
-		h = md5.New() //nolint:gosec // needed for compatibility
+		return md5.New(), nil //nolint:gosec // needed for compatibility
 	case enum.HashAlgSHA1:
-		h = sha1.New() //nolint:gosec // needed for compatibility
+		return sha1.New(), nil //nolint:gosec // needed for compatibility
 	case enum.HashAlgSHA256:
-		h = sha256.New()
+		return sha256.New(), nil
 	case enum.HashAlgSHA224:
-		h = sha256.New224()
+		return sha256.New224(), nil
 	case enum.HashAlgSHA384:
-		h = sha512.New384()
+		return sha512.New384(), nil
 	case enum.HashAlgSHA512:
-		h = sha512.New()
+		return sha512.New(), nil
 	case enum.HashAlgSHA512_224:
-		h = sha512.New512_224()
+		return sha512.New512_224(), nil
 	case enum.HashAlgSHA512_256:
-		h = sha512.New512_256()
+		return sha512.New512_256(), nil
+	case enum.HashAlgCRC32:
+		return crc32.NewIEEE(), nil
+	case enum.HashAlgCRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), nil
+	case enum.HashAlgCRC64ISO:
+		return crc64.New(crc64.MakeTable(crc64.ISO)), nil
+	case enum.HashAlgCRC64ECMA:
+		return crc64.New(crc64.MakeTable(crc64.ECMA)), nil
+	case enum.HashAlgAdler32:
+		return adler32.New(), nil
+	case enum.HashAlgFNV32A:
+		return fnv.New32a(), nil
+	case enum.HashAlgFNV64A:
+		return fnv.New64a(), nil
+	case enum.HashAlgFNV128A:
+		return fnv.New128a(), nil
 	default:
-		return "", fmt.Errorf("unsupported hash algorithm: %v", algo)
+		return nil, fmt.Errorf("unsupported hash algorithm: %v", algo)
 	}
+}
 
+// openChecksumFile opens a file to be hashed, with the errors all checksum functions report
+func openChecksumFile(path string) (*os.File, error) {
 	f, err := os.Open(path) //nolint:gosec // path is provided by the caller
 	if err != nil {
 		if os.IsNotExist(err) {
-			return "", fmt.Errorf("file not found: %s", path)
+			return nil, fmt.Errorf("file not found: %s", path)
 		}
-		return "", fmt.Errorf("failed to open file %s: %w", path, err)
+		return nil, fmt.Errorf("failed to open file %s: %w", path, err)
 	}
-	defer func() { _ = f.Close() }()
-
-	if _, err := io.Copy(h, f); err != nil {
-		return "", fmt.Errorf("failed to read file %s for hashing: %w", path, err)
-	}
-
-	return hex.EncodeToString(h.Sum(nil)), nil
+	return f, nil
 }
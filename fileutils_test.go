@@ -269,6 +269,7 @@ func TestSanitizePath(t *testing.T) {
 		{"  path/to/file.txt   ", "path/to/file.txt"},
 		{"path<>to|file?.txt", "path_to_file_.txt"},
 		{strings.Repeat("a", maxPathLength+10), strings.Repeat("a", maxPathLength)},
+		{"x" + strings.Repeat("ж", maxPathLength), "x" + strings.Repeat("ж", maxPathLength/2-1)}, // no split runes
 		{"path\\to/file.txt", "path/to/file.txt"},
 		{"con/nul", "con/nul"},
 	}
@@ -440,6 +441,128 @@ func TestTouchFile(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+func TestTouchFileWith(t *testing.T) {
+	atime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	mtime := time.Date(2021, 6, 7, 8, 9, 10, 0, time.UTC)
+	old := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// newFile creates a file with both times set to old
+	newFile := func(t *testing.T) string {
+		path := filepath.Join(t.TempDir(), "file.txt")
+		require.NoError(t, os.WriteFile(path, []byte("test"), 0o600))
+		require.NoError(t, os.Chtimes(path, old, old))
+		return path
+	}
+
+	assertTimes := func(t *testing.T, path string, wantAtime, wantMtime time.Time) {
+		t.Helper()
+		gotAtime, gotMtime, err := fileTimes(path, true)
+		require.NoError(t, err)
+		assert.True(t, wantAtime.Equal(gotAtime), "access time %v, expected %v", gotAtime, wantAtime)
+		assert.True(t, wantMtime.Equal(gotMtime), "modification time %v, expected %v", gotMtime, wantMtime)
+	}
+
+	t.Run("explicit times", func(t *testing.T) {
+		path := newFile(t)
+		require.NoError(t, TouchFileWith(path, TouchOptions{AccessTime: atime, ModTime: mtime}))
+		assertTimes(t, path, atime, mtime)
+	})
+
+	t.Run("access time only", func(t *testing.T) {
+		path := newFile(t)
+		require.NoError(t, TouchFileWith(path, TouchOptions{AccessTime: atime, ModTime: mtime, OnlyAccess: true}))
+		assertTimes(t, path, atime, old)
+	})
+
+	t.Run("modification time only", func(t *testing.T) {
+		path := newFile(t)
+		require.NoError(t, TouchFileWith(path, TouchOptions{AccessTime: atime, ModTime: mtime, OnlyMod: true}))
+		assertTimes(t, path, old, mtime)
+	})
+
+	t.Run("current time", func(t *testing.T) {
+		path := newFile(t)
+		before := time.Now().Add(-time.Second)
+		require.NoError(t, TouchFileWith(path, TouchOptions{OnlyMod: true}))
+		gotAtime, gotMtime, err := fileTimes(path, false)
+		require.NoError(t, err)
+		assert.True(t, gotAtime.Equal(old), "access time should be kept")
+		assert.True(t, gotMtime.After(before), "modification time should be current")
+	})
+
+	t.Run("reference file", func(t *testing.T) {
+		ref := newFile(t)
+		require.NoError(t, os.Chtimes(ref, atime, mtime))
+		path := newFile(t)
+		require.NoError(t, TouchFileWith(path, TouchOptions{Reference: ref}))
+		assertTimes(t, path, atime, mtime)
+
+		// an explicit time wins over the reference
+		require.NoError(t, TouchFileWith(path, TouchOptions{Reference: ref, ModTime: old}))
+		assertTimes(t, path, atime, old)
+
+		err := TouchFileWith(path, TouchOptions{Reference: filepath.Join(t.TempDir(), "missing")})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "reference file")
+	})
+
+	t.Run("no create", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "missing.txt")
+		require.NoError(t, TouchFileWith(path, TouchOptions{NoCreate: true}))
+		assert.False(t, IsFile(path))
+	})
+
+	t.Run("create with mode and times", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "new.txt")
+		require.NoError(t, TouchFileWith(path, TouchOptions{Mode: 0o600, AccessTime: atime, ModTime: mtime}))
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+		assert.Equal(t, int64(0), info.Size())
+		assertTimes(t, path, atime, mtime)
+
+		// parent directories are created only if asked for
+		err = TouchFileWith(filepath.Join(dir, "sub", "new.txt"), TouchOptions{})
+		require.Error(t, err)
+		require.NoError(t, TouchFileWith(filepath.Join(dir, "sub", "new.txt"), TouchOptions{CreateDirs: true}))
+		assert.True(t, IsFile(filepath.Join(dir, "sub", "new.txt")))
+	})
+
+	t.Run("existing content is kept", func(t *testing.T) {
+		path := newFile(t)
+		require.NoError(t, TouchFileWith(path, TouchOptions{Mode: 0o644}))
+		content, err := os.ReadFile(path) //nolint:gosec
+		require.NoError(t, err)
+		assert.Equal(t, "test", string(content))
+	})
+
+	t.Run("symlink itself", func(t *testing.T) {
+		target := newFile(t)
+		link := filepath.Join(t.TempDir(), "link")
+		require.NoError(t, os.Symlink(target, link))
+
+		require.NoError(t, TouchFileWith(link, TouchOptions{AccessTime: atime, ModTime: mtime, NoDereference: true}))
+		assertTimes(t, link, atime, mtime)
+		assertTimes(t, target, old, old)
+
+		// without NoDereference the target is touched
+		require.NoError(t, TouchFileWith(link, TouchOptions{AccessTime: mtime, ModTime: atime}))
+		assertTimes(t, target, mtime, atime)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		err := TouchFileWith("", TouchOptions{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "empty path")
+
+		err = TouchFileWith("/dev/null/invalid", TouchOptions{})
+		require.Error(t, err)
+	})
+}
+
 func TestChecksum(t *testing.T) {
 	// create a temporary test file
 	tmpDir := t.TempDir()
@@ -506,6 +629,27 @@ func TestChecksum(t *testing.T) {
 		assert.Equal(t, expectedSHA512_256, checksum)
 	})
 
+	t.Run("non-cryptographic", func(t *testing.T) {
+		tbl := []struct {
+			algo enum.HashAlg
+			sum  string
+		}{
+			{enum.HashAlgCRC32, "b9bdb31d"},
+			{enum.HashAlgCRC32C, "6c78426e"},
+			{enum.HashAlgCRC64ISO, "0b80f56bcce52f16"},
+			{enum.HashAlgCRC64ECMA, "ce5c30f22ad539f8"},
+			{enum.HashAlgAdler32, "653e105f"},
+			{enum.HashAlgFNV32A, "afb59e81"},
+			{enum.HashAlgFNV64A, "9de219ebdf4acc81"},
+			{enum.HashAlgFNV128A, "5e5dcf73cbdd761e9012cecdebfdc041"},
+		}
+		for _, tt := range tbl {
+			checksum, err := Checksum(testFile, tt.algo)
+			require.NoError(t, err)
+			assert.Equal(t, tt.sum, checksum, tt.algo.String())
+		}
+	})
+
 	t.Run("parse from string", func(t *testing.T) {
 		// test parsing from string
 		alg, err := enum.ParseHashAlg("sha256")
@@ -515,6 +659,26 @@ func TestChecksum(t *testing.T) {
 		assert.Equal(t, expectedSHA256, checksum)
 	})
 
+	t.Run("text and sql round trip", func(t *testing.T) {
+		for _, algo := range enum.HashAlgValues() {
+			text, err := algo.MarshalText()
+			require.NoError(t, err)
+			var parsed enum.HashAlg
+			require.NoError(t, parsed.UnmarshalText(text))
+			assert.Equal(t, algo, parsed)
+
+			value, err := algo.Value()
+			require.NoError(t, err)
+			var scanned enum.HashAlg
+			require.NoError(t, scanned.Scan([]byte(value.(string))))
+			assert.Equal(t, algo, scanned)
+		}
+
+		alg, err := enum.ParseHashAlg("crc64iso")
+		require.NoError(t, err)
+		assert.Equal(t, enum.HashAlgCRC64ISO, alg)
+	})
+
 	t.Run("errors", func(t *testing.T) {
 		_, err := Checksum("nonexistent.txt", enum.HashAlgMD5)
 		require.Error(t, err)
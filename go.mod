@@ -3,11 +3,12 @@ module github.com/go-pkgz/fileutils
 go 1.19
 
 require (
+	// pinned, renamedFrom in file_watcher_move.go parses the debug format of fsnotify events,
+	// see TestRenamedFrom before upgrading
 	github.com/fsnotify/fsnotify v1.9.0
 	github.com/stretchr/testify v1.12.0
+	golang.org/x/sys v0.30.0
+	golang.org/x/text v0.22.0
 )
 
-require (
-	golang.org/x/sys v0.30.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
-)
+require gopkg.in/yaml.v3 v3.0.1 // indirect
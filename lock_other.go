@@ -0,0 +1,8 @@
+//go:build !unix && !windows
+
+package fileutils
+
+// lockFile does nothing, there are no file locks on this platform
+func lockFile(string) (unlock func(), err error) {
+	return func() {}, nil
+}
@@ -0,0 +1,26 @@
+//go:build unix
+
+package fileutils
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockFile takes an exclusive lock of the file at path, creating it if needed, and waits for other processes
+// holding it. The lock is released by the returned function. POSIX locks are per process, so they don't
+// exclude other goroutines of the same process.
+func lockFile(path string) (unlock func(), err error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600) //nolint:gosec // lock file in a directory given by the caller
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %s: %w", path, err)
+	}
+	lock := unix.Flock_t{Type: unix.F_WRLCK} // zero start and length lock the whole file
+	if err := unix.FcntlFlock(f.Fd(), unix.F_SETLKW, &lock); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return func() { _ = f.Close() }, nil // closing the file releases the lock
+}
@@ -0,0 +1,228 @@
+package fileutils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+var (
+	reManifestGNU = regexp.MustCompile(`^([0-9a-fA-F]+) ([ *])(.+)$`)       // "<hex>  name" or "<hex> *name" for binary mode
+	reManifestBSD = regexp.MustCompile(`^(\S+) \((.+)\) = ([0-9a-fA-F]+)$`) // "SHA256 (name) = <hex>"
+)
+
+// manifestHexLengths maps the checksum length of GNU style lines, which don't name the algorithm, to the algorithm
+var manifestHexLengths = map[int]enum.HashAlg{
+	32:  enum.HashAlgMD5,
+	40:  enum.HashAlgSHA1,
+	56:  enum.HashAlgSHA224,
+	64:  enum.HashAlgSHA256,
+	96:  enum.HashAlgSHA384,
+	128: enum.HashAlgSHA512,
+}
+
+// ManifestReport is the result of VerifyManifest, all paths are slash-separated and relative to the directory
+type ManifestReport struct {
+	Matched    []string // files with the listed checksum
+	Mismatched []string // files with a checksum different from the listed one
+	Missing    []string // files listed in the manifest, but not found
+	Extra      []string // files found, but not listed in the manifest
+}
+
+// OK checks if every listed file matches and no file is missing or extra
+func (r *ManifestReport) OK() bool {
+	return len(r.Mismatched) == 0 && len(r.Missing) == 0 && len(r.Extra) == 0
+}
+
+// WriteManifest writes checksums of all files in dir to w, in the format of sha256sum and the other GNU coreutils
+// tools, with paths relative to dir. Names containing a backslash or a line break are escaped the way the tools do.
+// Algorithms VerifyManifest can't tell from the checksum length, e.g. SHA512_256 or CRC32, are named on every line
+// in the BSD format, "SHA512_256 (name) = <hex>".
+func WriteManifest(dir string, algo enum.HashAlg, w io.Writer) error {
+	list, err := ListFiles(dir)
+	if err != nil {
+		return fmt.Errorf("can't list files in %s: %w", dir, err)
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, file := range list {
+		sum, err := Checksum(file, algo)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return fmt.Errorf("can't get relative path of %s: %w", file, err)
+		}
+
+		name, escaped := escapeManifestName(filepath.ToSlash(rel))
+		if escaped {
+			_ = bw.WriteByte('\\')
+		}
+		format := "%[1]s  %[2]s\n"
+		if manifestHexLengths[len(sum)] != algo {
+			format = "%[3]s (%[2]s) = %[1]s\n"
+		}
+		if _, err := fmt.Fprintf(bw, format, sum, name, algo); err != nil {
+			return fmt.Errorf("failed to write manifest: %w", err)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+// VerifyManifest checks the files in dir against a manifest read from r, reporting matched, mismatched,
+// missing and extra files. Both the GNU format, as written by WriteManifest, and the BSD one, as written
+// by "sha256sum --tag" and BSD tools, are accepted, with escaped names and binary mode markers.
+// BSD lines name the algorithm, which can be a built-in or a registered one, see HashByName,
+// while for GNU lines it is told from the checksum length, one of MD5, SHA1, SHA224, SHA256, SHA384 and SHA512.
+// Empty lines and lines starting with "#" are skipped. A manifest stored in dir itself shows up as extra.
+func VerifyManifest(dir string, r io.Reader) (*ManifestReport, error) {
+	entries, err := parseManifest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ManifestReport{}
+	listed := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		path, err := SafeJoin(dir, e.name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path in manifest: %w", err)
+		}
+		rel := filepath.ToSlash(filepath.Clean(e.name))
+		listed[rel] = true
+
+		if !IsFile(path) {
+			report.Missing = append(report.Missing, rel)
+			continue
+		}
+		h, err := HashByName(e.algo)
+		if err != nil {
+			return nil, err
+		}
+		sum, err := checksumFile(path, h)
+		if err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(sum, e.sum) {
+			report.Matched = append(report.Matched, rel)
+		} else {
+			report.Mismatched = append(report.Mismatched, rel)
+		}
+	}
+
+	list, err := ListFiles(dir)
+	if err != nil {
+		return nil, fmt.Errorf("can't list files in %s: %w", dir, err)
+	}
+	for _, file := range list {
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return nil, fmt.Errorf("can't get relative path of %s: %w", file, err)
+		}
+		if rel = filepath.ToSlash(rel); !listed[rel] {
+			report.Extra = append(report.Extra, rel)
+		}
+	}
+
+	sort.Strings(report.Matched)
+	sort.Strings(report.Mismatched)
+	sort.Strings(report.Missing)
+	return report, nil
+}
+
+// manifestEntry is a single checksum line of a manifest
+type manifestEntry struct {
+	name string // file name, unescaped
+	algo string // hash algorithm name
+	sum  string // hex encoded checksum
+}
+
+// parseManifest reads all checksum lines of a manifest in GNU or BSD format
+func parseManifest(r io.Reader) ([]manifestEntry, error) {
+	var res []manifestEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// a leading backslash marks a line with an escaped name
+		escaped := strings.HasPrefix(line, `\`)
+		if escaped {
+			line = line[1:]
+		}
+
+		var e manifestEntry
+		if m := reManifestBSD.FindStringSubmatch(line); m != nil {
+			// BSD tools name SHA512/256 with a slash, the enum with an underscore
+			e = manifestEntry{name: m[2], algo: strings.ReplaceAll(m[1], "/", "_"), sum: m[3]}
+		} else if m := reManifestGNU.FindStringSubmatch(line); m != nil {
+			algo, ok := manifestHexLengths[len(m[1])]
+			if !ok {
+				return nil, fmt.Errorf("unknown checksum length %d in manifest line %d", len(m[1]), lineNum)
+			}
+			e = manifestEntry{name: m[3], algo: algo.String(), sum: m[1]}
+		} else {
+			return nil, fmt.Errorf("invalid manifest line %d: %q", lineNum, line)
+		}
+
+		if escaped {
+			name, err := unescapeManifestName(e.name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid manifest line %d: %w", lineNum, err)
+			}
+			e.name = name
+		}
+		res = append(res, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return res, nil
+}
+
+// escapeManifestName escapes backslashes and line breaks in a name the way coreutils does,
+// reporting if the name needed escaping, which has to be marked by a leading backslash on the line
+func escapeManifestName(name string) (string, bool) {
+	if !strings.ContainsAny(name, "\\\n\r") {
+		return name, false
+	}
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`).Replace(name), true
+}
+
+// unescapeManifestName reverses escapeManifestName
+func unescapeManifestName(name string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] != '\\' {
+			sb.WriteByte(name[i])
+			continue
+		}
+		if i++; i == len(name) {
+			return "", fmt.Errorf("dangling escape in %q", name)
+		}
+		switch name[i] {
+		case '\\':
+			sb.WriteByte('\\')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		default:
+			return "", fmt.Errorf("unknown escape \\%c in %q", name[i], name)
+		}
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,146 @@
+package fileutils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+// writeTestTree creates files with the given slash-separated names and content under dir
+func writeTestTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+}
+
+func TestWriteManifest(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]string{
+		"checksum_test.txt": checksumTestContent,
+		"sub/empty.txt":     "",
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteManifest(dir, enum.HashAlgSHA256, &buf))
+	assert.Equal(t, checksumTestSHA256+"  checksum_test.txt\n"+
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  sub/empty.txt\n", buf.String())
+
+	t.Run("escaped names", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestTree(t, dir, map[string]string{"a\\b\nc.txt": ""})
+
+		var buf bytes.Buffer
+		require.NoError(t, WriteManifest(dir, enum.HashAlgMD5, &buf))
+		assert.Equal(t, "\\d41d8cd98f00b204e9800998ecf8427e  a\\\\b\\nc.txt\n", buf.String())
+	})
+
+	t.Run("algorithm not told by the checksum length", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteManifest(dir, enum.HashAlgSHA512_256, &buf))
+		sum, err := Checksum(filepath.Join(dir, "checksum_test.txt"), enum.HashAlgSHA512_256)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(buf.String(), "SHA512_256 (checksum_test.txt) = "+sum+"\n"), buf.String())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		require.Error(t, WriteManifest(filepath.Join(dir, "missing"), enum.HashAlgSHA256, &bytes.Buffer{}))
+		require.Error(t, WriteManifest(dir, enum.HashAlg{}, &bytes.Buffer{}))
+		require.Error(t, WriteManifest(dir, enum.HashAlgSHA256, &limitedWriter{limit: 10}))
+	})
+}
+
+func TestVerifyManifest(t *testing.T) {
+	files := map[string]string{
+		"a.txt":         "file a",
+		"sub/b.txt":     "file b",
+		"sub/c d.txt":   "file c",
+		"odd\\name\n.x": "file with escaped name",
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		for _, algo := range enum.HashAlgValues() {
+			dir := t.TempDir()
+			writeTestTree(t, dir, files)
+			var buf bytes.Buffer
+			require.NoError(t, WriteManifest(dir, algo, &buf))
+
+			report, err := VerifyManifest(dir, &buf)
+			require.NoError(t, err, algo.String())
+			assert.True(t, report.OK(), "%s: %+v", algo, report)
+			assert.Equal(t, []string{"a.txt", "odd\\name\n.x", "sub/b.txt", "sub/c d.txt"}, report.Matched)
+		}
+	})
+
+	t.Run("changes", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestTree(t, dir, files)
+		var buf bytes.Buffer
+		require.NoError(t, WriteManifest(dir, enum.HashAlgSHA256, &buf))
+
+		writeTestTree(t, dir, map[string]string{"a.txt": "changed", "new.txt": "added"})
+		require.NoError(t, os.Remove(filepath.Join(dir, "sub", "b.txt")))
+
+		report, err := VerifyManifest(dir, &buf)
+		require.NoError(t, err)
+		assert.False(t, report.OK())
+		assert.Equal(t, &ManifestReport{
+			Matched:    []string{"odd\\name\n.x", "sub/c d.txt"},
+			Mismatched: []string{"a.txt"},
+			Missing:    []string{"sub/b.txt"},
+			Extra:      []string{"new.txt"},
+		}, report)
+	})
+
+	t.Run("bsd format and binary mode", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestTree(t, dir, map[string]string{"checksum_test.txt": checksumTestContent, "x\ny.txt": checksumTestContent})
+
+		manifest := strings.Join([]string{
+			"# release checksums",
+			"",
+			"SHA256 (checksum_test.txt) = " + strings.ToUpper(checksumTestSHA256),
+			`\MD5 (x\ny.txt) = ` + checksumTestMD5,
+			checksumTestMD5 + " *./checksum_test.txt",
+			"SHA512/256 (checksum_test.txt) = b5bc9721b180d5c79264f5fbb61404b516b6bfcb486c95b65329a1fe71ff6728",
+		}, "\r\n")
+		report, err := VerifyManifest(dir, strings.NewReader(manifest))
+		require.NoError(t, err)
+		assert.True(t, report.OK(), "%+v", report)
+		assert.Equal(t, []string{"checksum_test.txt", "checksum_test.txt", "checksum_test.txt", "x\ny.txt"}, report.Matched)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestTree(t, dir, map[string]string{"a.txt": "file a"})
+
+		tbl := []struct {
+			manifest string
+			wantErr  string
+		}{
+			{"not a checksum line", "invalid manifest line 1"},
+			{"abcd  a.txt", "unknown checksum length 4 in manifest line 1"},
+			{"\n" + checksumTestMD5 + "  ../outside.txt", "invalid path in manifest"},
+			{"\\" + checksumTestMD5 + "  a\\q.txt", "unknown escape"},
+			{"\\" + checksumTestMD5 + "  a.txt\\", "dangling escape"},
+			{"UNKNOWN (a.txt) = " + checksumTestMD5, "unsupported hash algorithm"},
+		}
+		for _, tt := range tbl {
+			_, err := VerifyManifest(dir, strings.NewReader(tt.manifest))
+			require.Error(t, err, tt.manifest)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		}
+
+		_, err := VerifyManifest(filepath.Join(dir, "missing"), strings.NewReader(""))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,100 @@
+package fileutils
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+//go:generate enum -type=normForm -path=enum
+
+// normForm is a Unicode normalization form
+//
+//nolint:unused // This type is used by the enum generator
+type normForm int
+
+// Normalization forms
+//
+//nolint:unused // These constants are used by the enum generator
+const (
+	normFormNFC normForm = iota + 1
+	normFormNFD
+	normFormNFKC
+	normFormNFKD
+)
+
+// NormalizePath returns the path with its names converted to the given Unicode normalization form.
+// Files created on macOS usually carry NFD names, while Linux tools and most input produce NFC,
+// so the same name can arrive in both forms. An unknown form is treated as NFC.
+func NormalizePath(path string, form enum.NormForm) string {
+	switch form {
+	case enum.NormFormNFD:
+		return norm.NFD.String(path)
+	case enum.NormFormNFKC:
+		return norm.NFKC.String(path)
+	case enum.NormFormNFKD:
+		return norm.NFKD.String(path)
+	default:
+		return norm.NFC.String(path)
+	}
+}
+
+// NameCollision is a group of paths which would end up as the same path on a target normalizing names
+type NameCollision struct {
+	Key   string   // path the group collapses to, NFC normalized and, if checked, case-folded
+	Paths []string // colliding paths, slash-separated, relative to the scanned directory and sorted
+}
+
+// FindNameCollisions scans the files under dir, and the directories holding them, for paths differing only
+// in Unicode normalization and, with caseInsensitive set, in case. Such paths are distinct in dir,
+// but collide once copied to a normalizing target like macOS, or a case-insensitive one like Windows.
+// Both a colliding directory and the colliding paths under it are reported, sorted by key.
+func FindNameCollisions(dir string, caseInsensitive bool) ([]NameCollision, error) {
+	list, err := ListFiles(dir)
+	if err != nil {
+		return nil, fmt.Errorf("can't list files in %s: %w", dir, err)
+	}
+
+	fold := cases.Fold()
+	groups := map[string]map[string]bool{} // key to the set of paths collapsing to it
+	for _, file := range list {
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return nil, fmt.Errorf("can't get relative path of %s: %w", file, err)
+		}
+		// the directories holding the file collide just as well, check every prefix of the path
+		elems := strings.Split(filepath.ToSlash(rel), "/")
+		for i := range elems {
+			path := strings.Join(elems[:i+1], "/")
+			key := norm.NFC.String(path)
+			if caseInsensitive {
+				key = fold.String(key)
+			}
+			if groups[key] == nil {
+				groups[key] = map[string]bool{}
+			}
+			groups[key][path] = true
+		}
+	}
+
+	var res []NameCollision
+	for key, paths := range groups {
+		if len(paths) < 2 {
+			continue
+		}
+		c := NameCollision{Key: key}
+		for path := range paths {
+			c.Paths = append(c.Paths, path)
+		}
+		sort.Strings(c.Paths)
+		res = append(res, c)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Key < res[j].Key })
+	return res, nil
+}
@@ -0,0 +1,67 @@
+package fileutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+const (
+	cafeNFC = "café"  // "é" as a single code point
+	cafeNFD = "café" // "e" followed by a combining acute accent
+)
+
+func TestNormalizePath(t *testing.T) {
+	assert.Equal(t, "dir/"+cafeNFC+".txt", NormalizePath("dir/"+cafeNFD+".txt", enum.NormFormNFC))
+	assert.Equal(t, "dir/"+cafeNFD+".txt", NormalizePath("dir/"+cafeNFC+".txt", enum.NormFormNFD))
+	assert.Equal(t, "file.txt", NormalizePath("ﬁle.txt", enum.NormFormNFKC)) // "fi" ligature
+	assert.Equal(t, "file.txt", NormalizePath("ﬁle.txt", enum.NormFormNFKD))
+	assert.Equal(t, "ﬁle.txt", NormalizePath("ﬁle.txt", enum.NormFormNFC))
+	assert.Equal(t, cafeNFC, NormalizePath(cafeNFD, enum.NormForm{}), "unknown form defaults to NFC")
+}
+
+func TestFindNameCollisions(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		"README.md",
+		"readme.md",
+		cafeNFC + ".txt",
+		cafeNFD + ".txt",
+		"Docs/a.txt",
+		"docs/b.txt",
+		"unique.txt",
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+		require.NoError(t, os.WriteFile(path, []byte(f), 0o600))
+	}
+
+	t.Run("normalization only", func(t *testing.T) {
+		res, err := FindNameCollisions(dir, false)
+		require.NoError(t, err)
+		assert.Equal(t, []NameCollision{
+			{Key: cafeNFC + ".txt", Paths: []string{cafeNFD + ".txt", cafeNFC + ".txt"}},
+		}, res)
+	})
+
+	t.Run("case insensitive", func(t *testing.T) {
+		res, err := FindNameCollisions(dir, true)
+		require.NoError(t, err)
+		assert.Equal(t, []NameCollision{
+			{Key: cafeNFC + ".txt", Paths: []string{cafeNFD + ".txt", cafeNFC + ".txt"}},
+			{Key: "docs", Paths: []string{"Docs", "docs"}}, // the files in them differ, the directories still merge
+			{Key: "readme.md", Paths: []string{"README.md", "readme.md"}},
+		}, res)
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		_, err := FindNameCollisions(filepath.Join(dir, "missing"), true)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,132 @@
+package fileutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// errTmpfileUnsupported is returned by publishTmpfile when anonymous temporary files can't be used,
+// either on this platform or on the filesystem of the target directory
+var errTmpfileUnsupported = errors.New("anonymous temporary files are not supported")
+
+// errNoReplaceUnsupported is returned by renameNoReplace when a rename can't be told not to replace the target,
+// either on this platform or on the filesystem of the target directory
+var errNoReplaceUnsupported = errors.New("renames without replacing are not supported")
+
+// linkFile makes a hard link, a variable for tests to simulate filesystems without hard links
+var linkFile = os.Link
+
+// PublishFile creates the file at path with the content written by the write function.
+// The file shows up at path only once write succeeded and the data is synced to disk,
+// so readers never see it partially written, and a failed write leaves nothing behind.
+// An existing file at path is replaced if replace is set, otherwise PublishFile fails
+// with an error matching os.ErrExist and the existing file is left untouched.
+// On Linux the data goes to an unnamed O_TMPFILE file linked into place when complete,
+// elsewhere, or if the filesystem doesn't support it, to a temporary file next to path. Without replace
+// that file is hard linked to path, on filesystems without hard links on Linux it's renamed without replacing.
+// The mode of the new file is perm, filtered by umask.
+func PublishFile(path string, perm os.FileMode, replace bool, write func(f *os.File) error) error {
+	if path == "" {
+		return errors.New("empty path")
+	}
+	if write == nil {
+		return errors.New("write function is required")
+	}
+
+	err := publishTmpfile(path, perm, replace, write)
+	if errors.Is(err, errTmpfileUnsupported) {
+		return publishTempName(path, perm, replace, write)
+	}
+	return err
+}
+
+// publishTempName is the portable PublishFile, writing to a named temporary file in the target directory.
+// The file is renamed over path when replacing, and hard linked otherwise, as the link fails on an existing path.
+// Where the filesystem has no hard links, it's renamed without replacing instead, if the system supports it.
+func publishTempName(path string, perm os.FileMode, replace bool, write func(f *os.File) error) error {
+	f, err := createTempSibling(path, perm)
+	if err != nil {
+		return err
+	}
+	tmpName := f.Name()
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(tmpName) // nothing to remove after the rename, and only the link is kept otherwise
+	}()
+
+	if err = write(f); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err = f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", tmpName, err)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", tmpName, err)
+	}
+
+	if replace {
+		err = os.Rename(tmpName, path)
+	} else {
+		err = publishNoReplace(tmpName, path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to publish %s: %w", path, err)
+	}
+
+	return syncDir(filepath.Dir(path))
+}
+
+// publishNoReplace gives the complete temporary file the name path unless it exists, with a hard link,
+// or with a rename not replacing path if the link fails for another reason, e.g. no hard links on vfat
+func publishNoReplace(tmpName, path string) error {
+	err := linkFile(tmpName, path)
+	if err == nil || errors.Is(err, os.ErrExist) {
+		return err
+	}
+	renameErr := renameNoReplace(tmpName, path)
+	if errors.Is(renameErr, errNoReplaceUnsupported) {
+		return fmt.Errorf("can't publish %s without replacing, neither hard links nor renames without replacing "+
+			"are supported: %w", path, err)
+	}
+	return renameErr
+}
+
+// createTempSibling creates a new hidden temporary file in the directory of path,
+// named after it so a leftover can be traced back to the file it was meant to become
+func createTempSibling(path string, perm os.FileMode) (*os.File, error) {
+	dir, base := filepath.Split(path)
+	const maxTries = 10
+	for i := 0; ; i++ {
+		name, err := TempFileName(dir, "."+base+".*.tmp")
+		if err != nil {
+			return nil, err
+		}
+		// the name was free when generated, O_EXCL makes sure nobody took it since
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm) //nolint:gosec // name is derived from the caller's path
+		if err == nil {
+			return f, nil
+		}
+		if !os.IsExist(err) || i == maxTries-1 {
+			return nil, fmt.Errorf("failed to create temporary file for %s: %w", path, err)
+		}
+	}
+}
+
+// syncDir flushes directory entries, making a rename or link done in dir durable
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil // directories can't be opened for syncing there, renames are journaled by NTFS
+	}
+	d, err := os.Open(dir) //nolint:gosec // dir is derived from the caller's path
+	if err != nil {
+		return fmt.Errorf("failed to open directory %s: %w", dir, err)
+	}
+	defer func() { _ = d.Close() }()
+	if err = d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync directory %s: %w", dir, err)
+	}
+	return nil
+}
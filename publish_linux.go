@@ -0,0 +1,92 @@
+package fileutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// publishTmpfile writes the file as an unnamed O_TMPFILE inode in the target directory and links it into place.
+// Returns errTmpfileUnsupported before calling write if the kernel, the filesystem or the missing procfs rule it out.
+func publishTmpfile(path string, perm os.FileMode, replace bool, write func(f *os.File) error) error {
+	// linking an unnamed file by descriptor goes through /proc/self/fd, as AT_EMPTY_PATH needs CAP_DAC_READ_SEARCH
+	if _, err := os.Stat("/proc/self/fd"); err != nil {
+		return errTmpfileUnsupported
+	}
+
+	dir := filepath.Dir(path)
+	fd, err := unix.Open(dir, unix.O_TMPFILE|unix.O_WRONLY|unix.O_CLOEXEC, uint32(perm.Perm()))
+	if err != nil {
+		// kernels predating O_TMPFILE see a plain directory open and fail with EISDIR,
+		// filesystems without support for it fail with EOPNOTSUPP
+		if errors.Is(err, unix.EISDIR) || errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.EINVAL) {
+			return errTmpfileUnsupported
+		}
+		return fmt.Errorf("failed to create temporary file in %s: %w", dir, &os.PathError{Op: "open", Path: dir, Err: err})
+	}
+	f := os.NewFile(uintptr(fd), path)
+	defer func() { _ = f.Close() }()
+
+	if err = write(f); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err = f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", path, err)
+	}
+
+	if err = linkTmpfile(f, path, replace); err != nil {
+		return fmt.Errorf("failed to publish %s: %w", path, err)
+	}
+
+	return syncDir(dir)
+}
+
+// linkTmpfile gives the unnamed file f the name path. linkat never replaces an existing file,
+// so for replace the file is linked under a temporary name first and renamed over path.
+func linkTmpfile(f *os.File, path string, replace bool) error {
+	fdPath := "/proc/self/fd/" + strconv.Itoa(int(f.Fd()))
+
+	if !replace {
+		if err := unix.Linkat(unix.AT_FDCWD, fdPath, unix.AT_FDCWD, path, unix.AT_SYMLINK_FOLLOW); err != nil {
+			return &os.LinkError{Op: "linkat", Old: fdPath, New: path, Err: err}
+		}
+		return nil
+	}
+
+	const maxTries = 10
+	for i := 0; ; i++ {
+		tmpName, err := TempFileName(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+		if err != nil {
+			return err
+		}
+		err = unix.Linkat(unix.AT_FDCWD, fdPath, unix.AT_FDCWD, tmpName, unix.AT_SYMLINK_FOLLOW)
+		if errors.Is(err, unix.EEXIST) && i < maxTries-1 {
+			continue // the generated name was taken in the meantime
+		}
+		if err != nil {
+			return &os.LinkError{Op: "linkat", Old: fdPath, New: tmpName, Err: err}
+		}
+		if err = os.Rename(tmpName, path); err != nil {
+			_ = os.Remove(tmpName)
+			return err
+		}
+		return nil
+	}
+}
+
+// renameNoReplace renames oldpath to newpath with renameat2, failing with an error matching os.ErrExist
+// if newpath exists. Returns errNoReplaceUnsupported if the kernel or the filesystem doesn't support it.
+func renameNoReplace(oldpath, newpath string) error {
+	err := unix.Renameat2(unix.AT_FDCWD, oldpath, unix.AT_FDCWD, newpath, unix.RENAME_NOREPLACE)
+	if errors.Is(err, unix.ENOSYS) || errors.Is(err, unix.EINVAL) || errors.Is(err, unix.EOPNOTSUPP) {
+		return errNoReplaceUnsupported
+	}
+	if err != nil {
+		return &os.LinkError{Op: "renameat2", Old: oldpath, New: newpath, Err: err}
+	}
+	return nil
+}
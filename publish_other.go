@@ -0,0 +1,15 @@
+//go:build !linux
+
+package fileutils
+
+import "os"
+
+// publishTmpfile always reports O_TMPFILE as unsupported, it is Linux-only
+func publishTmpfile(string, os.FileMode, bool, func(f *os.File) error) error {
+	return errTmpfileUnsupported
+}
+
+// renameNoReplace always reports renames without replacing as unsupported, they are implemented on Linux only
+func renameNoReplace(string, string) error {
+	return errNoReplaceUnsupported
+}
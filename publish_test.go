@@ -0,0 +1,152 @@
+package fileutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishFile(t *testing.T) {
+	// PublishFile picks O_TMPFILE where it can, the named temporary file is checked on its own
+	publishers := []struct {
+		name    string
+		publish func(path string, perm os.FileMode, replace bool, write func(f *os.File) error) error
+	}{
+		{"PublishFile", PublishFile},
+		{"temporary name", publishTempName},
+	}
+
+	writeString := func(s string) func(f *os.File) error {
+		return func(f *os.File) error {
+			_, err := f.WriteString(s)
+			return err
+		}
+	}
+
+	for _, p := range publishers {
+		p := p
+		t.Run(p.name, func(t *testing.T) {
+			t.Run("new file", func(t *testing.T) {
+				dir := t.TempDir()
+				path := filepath.Join(dir, "new.txt")
+				require.NoError(t, p.publish(path, 0o600, false, writeString("new content")))
+
+				content, err := os.ReadFile(path) //nolint:gosec
+				require.NoError(t, err)
+				assert.Equal(t, "new content", string(content))
+
+				info, err := os.Stat(path)
+				require.NoError(t, err)
+				assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+				assertOnlyFiles(t, dir, "new.txt")
+			})
+
+			t.Run("replace existing", func(t *testing.T) {
+				dir := t.TempDir()
+				path := filepath.Join(dir, "existing.txt")
+				require.NoError(t, os.WriteFile(path, []byte("old content"), 0o600))
+
+				require.NoError(t, p.publish(path, 0o600, true, writeString("new content")))
+
+				content, err := os.ReadFile(path) //nolint:gosec
+				require.NoError(t, err)
+				assert.Equal(t, "new content", string(content))
+				assertOnlyFiles(t, dir, "existing.txt")
+			})
+
+			t.Run("keep existing", func(t *testing.T) {
+				dir := t.TempDir()
+				path := filepath.Join(dir, "existing.txt")
+				require.NoError(t, os.WriteFile(path, []byte("old content"), 0o600))
+
+				err := p.publish(path, 0o600, false, writeString("new content"))
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, os.ErrExist), "unexpected error %v", err)
+
+				content, err := os.ReadFile(path) //nolint:gosec
+				require.NoError(t, err)
+				assert.Equal(t, "old content", string(content))
+				assertOnlyFiles(t, dir, "existing.txt")
+			})
+
+			t.Run("failed write", func(t *testing.T) {
+				dir := t.TempDir()
+				path := filepath.Join(dir, "failed.txt")
+				err := p.publish(path, 0o600, true, func(f *os.File) error {
+					_, _ = f.WriteString("partial")
+					return errors.New("generator failed")
+				})
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "generator failed")
+				assertOnlyFiles(t, dir)
+			})
+
+			t.Run("missing directory", func(t *testing.T) {
+				path := filepath.Join(t.TempDir(), "missing", "file.txt")
+				require.Error(t, p.publish(path, 0o600, true, writeString("content")))
+			})
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		err := PublishFile("", 0o600, false, func(*os.File) error { return nil })
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "empty path")
+
+		err = PublishFile(filepath.Join(t.TempDir(), "file.txt"), 0o600, false, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "write function is required")
+	})
+}
+
+func TestPublishFileWithoutHardLinks(t *testing.T) {
+	linkFile = func(oldname, newname string) error {
+		return &os.LinkError{Op: "link", Old: oldname, New: newname, Err: errors.New("operation not permitted")}
+	}
+	defer func() { linkFile = os.Link }()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "new.txt")
+	write := func(f *os.File) error {
+		_, err := f.WriteString("new content")
+		return err
+	}
+
+	err := publishTempName(path, 0o600, false, write)
+	if runtime.GOOS != "linux" {
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "neither hard links nor renames without replacing are supported")
+		assertOnlyFiles(t, dir)
+		return
+	}
+	require.NoError(t, err, "renamed without replacing")
+	content, err := os.ReadFile(path) //nolint:gosec
+	require.NoError(t, err)
+	assert.Equal(t, "new content", string(content))
+	assertOnlyFiles(t, dir, "new.txt")
+
+	err = publishTempName(path, 0o600, false, write)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, os.ErrExist), "unexpected error %v", err)
+	assertOnlyFiles(t, dir, "new.txt")
+}
+
+// assertOnlyFiles checks dir holds exactly the named entries, catching temporary files left behind
+func assertOnlyFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	found := []string{}
+	for _, e := range entries {
+		found = append(found, e.Name())
+	}
+	if names == nil {
+		names = []string{}
+	}
+	assert.Equal(t, names, found)
+}
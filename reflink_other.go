@@ -0,0 +1,13 @@
+//go:build !linux
+
+package fileutils
+
+import (
+	"errors"
+	"os"
+)
+
+// cloneFile always fails, reflinks are supported on Linux only
+func cloneFile(dst, src *os.File) error {
+	return &os.LinkError{Op: "ficlone", Old: src.Name(), New: dst.Name(), Err: errors.New("reflinks are not supported on this platform")}
+}
@@ -0,0 +1,154 @@
+package fileutils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// maxSymlinks limits the symlinks SafeJoinResolved follows, the same limit Linux uses for path lookups
+const maxSymlinks = 40
+
+// PathEscapeError is returned when a path joined to a root directory would end up outside of it
+type PathEscapeError struct {
+	Root string // root directory the path has to stay in
+	Path string // untrusted path
+	Link string // symlink leading outside of root, empty if the path itself escapes
+}
+
+// Error implements the error interface
+func (e *PathEscapeError) Error() string {
+	if e.Link != "" {
+		return fmt.Sprintf("path %q escapes %s via symlink %s", e.Path, e.Root, e.Link)
+	}
+	return fmt.Sprintf("path %q escapes %s", e.Path, e.Root)
+}
+
+// SafeJoin joins the untrusted path to root, making sure the result stays under root.
+// Absolute paths and ".." elements climbing above root are rejected with a *PathEscapeError,
+// ".." elements staying within root are fine. The check is lexical only, symlinks under root
+// may still point outside of it, use SafeJoinResolved if the tree can contain them.
+func SafeJoin(root, untrusted string) (string, error) {
+	if root == "" {
+		return "", errors.New("empty root path")
+	}
+	if strings.IndexByte(untrusted, 0) != -1 {
+		return "", fmt.Errorf("invalid path %q: contains NUL", untrusted)
+	}
+	if filepath.IsAbs(untrusted) || filepath.VolumeName(untrusted) != "" {
+		return "", &PathEscapeError{Root: root, Path: untrusted}
+	}
+
+	root = filepath.Clean(root)
+	joined := filepath.Join(root, untrusted)
+	if !isWithin(root, joined) {
+		return "", &PathEscapeError{Root: root, Path: untrusted}
+	}
+	return joined, nil
+}
+
+// SafeJoinResolved is SafeJoin that also follows symlinks, resolving the path one element at a time
+// the way openat does and failing with a *PathEscapeError if a symlink leads outside of root.
+// Symlinks staying inside root are fine, the returned path has them resolved.
+// Elements past the first missing one are joined lexically, so the result can name a file yet to be created.
+// The tree must not be modified concurrently by someone untrusted, as the check and the later use of the path race.
+func SafeJoinResolved(root, untrusted string) (string, error) {
+	if _, err := SafeJoin(root, untrusted); err != nil {
+		return "", err
+	}
+
+	root = filepath.Clean(root)
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve root %s: %w", root, err)
+	}
+	realRoot, err := filepath.EvalSymlinks(absRoot)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve root %s: %w", root, err)
+	}
+
+	resolved := ""                          // resolved part of the path, relative to root
+	pending := splitPathElements(untrusted) // elements still to resolve
+	link := ""                              // last symlink followed, reported if the path escapes after it
+	links := 0
+	for len(pending) > 0 {
+		elem := pending[0]
+		pending = pending[1:]
+
+		if elem == ".." {
+			if resolved == "" {
+				return "", &PathEscapeError{Root: root, Path: untrusted, Link: link}
+			}
+			if resolved = filepath.Dir(resolved); resolved == "." {
+				resolved = ""
+			}
+			continue
+		}
+
+		next := filepath.Join(resolved, elem)
+		info, err := os.Lstat(filepath.Join(realRoot, next))
+		// a missing element, or a file used as a directory, ends resolution, the rest is joined lexically
+		if err != nil && !os.IsNotExist(err) && !errors.Is(err, syscall.ENOTDIR) {
+			return "", fmt.Errorf("failed to stat %s: %w", filepath.Join(root, next), err)
+		}
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		if links++; links > maxSymlinks {
+			return "", fmt.Errorf("failed to resolve %q: too many levels of symbolic links", untrusted)
+		}
+		link = filepath.Join(root, next)
+		target, err := os.Readlink(filepath.Join(realRoot, next))
+		if err != nil {
+			return "", fmt.Errorf("failed to read symlink %s: %w", link, err)
+		}
+
+		if filepath.IsAbs(target) {
+			// an absolute target is fine as long as it points into root, named either way
+			rel, ok := relWithin(realRoot, target)
+			if !ok {
+				if rel, ok = relWithin(absRoot, target); !ok {
+					return "", &PathEscapeError{Root: root, Path: untrusted, Link: link}
+				}
+			}
+			resolved = ""
+			pending = append(splitPathElements(rel), pending...)
+			continue
+		}
+		// a relative target is resolved from the directory holding the link, which is where resolved is
+		pending = append(splitPathElements(target), pending...)
+	}
+
+	return filepath.Join(root, resolved), nil
+}
+
+// splitPathElements splits a path into its non-empty elements, dropping "." ones
+func splitPathElements(path string) []string {
+	var res []string
+	for _, elem := range strings.Split(filepath.ToSlash(path), "/") {
+		if elem != "" && elem != "." {
+			res = append(res, elem)
+		}
+	}
+	return res
+}
+
+// isWithin checks if the clean path is root or lies under it, lexically
+func isWithin(root, path string) bool {
+	_, ok := relWithin(root, path)
+	return ok
+}
+
+// relWithin returns path relative to root if it lies under root, lexically
+func relWithin(root, path string) (string, bool) {
+	rel, err := filepath.Rel(root, filepath.Clean(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
@@ -0,0 +1,133 @@
+package fileutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSafeJoin(t *testing.T) {
+	tbl := []struct {
+		root, untrusted string
+		out             string
+		escape          bool
+	}{
+		{root: "/srv/uploads", untrusted: "file.txt", out: "/srv/uploads/file.txt"},
+		{root: "/srv/uploads", untrusted: "a/b/../c.txt", out: "/srv/uploads/a/c.txt"},
+		{root: "/srv/uploads/", untrusted: "./a//b", out: "/srv/uploads/a/b"},
+		{root: "/srv/uploads", untrusted: "", out: "/srv/uploads"},
+		{root: "/srv/uploads", untrusted: "a/..", out: "/srv/uploads"},
+		{root: "uploads", untrusted: "a.txt", out: "uploads/a.txt"},
+		{root: "/srv/uploads", untrusted: "..", escape: true},
+		{root: "/srv/uploads", untrusted: "../uploads2/file.txt", escape: true},
+		{root: "/srv/uploads", untrusted: "a/../../etc/passwd", escape: true},
+		{root: "/srv/uploads", untrusted: "/etc/passwd", escape: true},
+		{root: "uploads", untrusted: "../uploads.txt", escape: true},
+	}
+
+	for _, tt := range tbl {
+		tt := tt
+		t.Run(tt.untrusted, func(t *testing.T) {
+			res, err := SafeJoin(tt.root, tt.untrusted)
+			if tt.escape {
+				var escErr *PathEscapeError
+				require.True(t, errors.As(err, &escErr), "expected escape error, got %v", err)
+				assert.Equal(t, tt.untrusted, escErr.Path)
+				assert.Empty(t, escErr.Link)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.out, res)
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := SafeJoin("", "file.txt")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "empty root path")
+
+		_, err = SafeJoin("/srv", "file\x00.txt")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "contains NUL")
+	})
+}
+
+func TestSafeJoinResolved(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "root")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "dir", "sub"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "dir", "file.txt"), []byte("content"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(base, "secret.txt"), []byte("secret"), 0o600))
+
+	require.NoError(t, os.Symlink("dir/sub", filepath.Join(root, "rel-inside")))
+	require.NoError(t, os.Symlink(filepath.Join(root, "dir"), filepath.Join(root, "abs-inside")))
+	require.NoError(t, os.Symlink("../secret.txt", filepath.Join(root, "rel-outside")))
+	require.NoError(t, os.Symlink(filepath.Join(base, "secret.txt"), filepath.Join(root, "abs-outside")))
+	require.NoError(t, os.Symlink("../../..", filepath.Join(root, "dir", "sub", "up-outside")))
+	require.NoError(t, os.Symlink("..", filepath.Join(root, "dir", "sub", "up-inside")))
+	require.NoError(t, os.Symlink("loop2", filepath.Join(root, "loop1")))
+	require.NoError(t, os.Symlink("loop1", filepath.Join(root, "loop2")))
+
+	tbl := []struct {
+		untrusted string
+		out       string
+		link      string // symlink reported by the escape error, if escaping
+	}{
+		{untrusted: "dir/file.txt", out: "dir/file.txt"},
+		{untrusted: "missing/new.txt", out: "missing/new.txt"},
+		{untrusted: "dir/file.txt/x", out: "dir/file.txt/x"},
+		{untrusted: "rel-inside/new.txt", out: "dir/sub/new.txt"},
+		{untrusted: "abs-inside/file.txt", out: "dir/file.txt"},
+		{untrusted: "dir/sub/up-inside/file.txt", out: "dir/file.txt"},
+		{untrusted: "rel-inside/../file.txt", out: "dir/file.txt"}, // ".." applies to the link target, as the kernel does
+		{untrusted: "rel-outside", link: "rel-outside"},
+		{untrusted: "abs-outside", link: "abs-outside"},
+		{untrusted: "dir/sub/up-outside/secret.txt", link: "dir/sub/up-outside"},
+		{untrusted: "rel-inside/up-outside", link: "dir/sub/up-outside"},
+	}
+
+	for _, tt := range tbl {
+		tt := tt
+		t.Run(tt.untrusted, func(t *testing.T) {
+			res, err := SafeJoinResolved(root, tt.untrusted)
+			if tt.link != "" {
+				var escErr *PathEscapeError
+				require.True(t, errors.As(err, &escErr), "expected escape error, got %v", err)
+				assert.Equal(t, filepath.Join(root, tt.link), escErr.Link)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, filepath.Join(root, tt.out), res)
+		})
+	}
+
+	t.Run("lexical escape", func(t *testing.T) {
+		_, err := SafeJoinResolved(root, "../secret.txt")
+		var escErr *PathEscapeError
+		require.True(t, errors.As(err, &escErr), "expected escape error, got %v", err)
+		assert.Empty(t, escErr.Link)
+	})
+
+	t.Run("symlink loop", func(t *testing.T) {
+		_, err := SafeJoinResolved(root, "loop1/file.txt")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "too many levels of symbolic links")
+	})
+
+	t.Run("root reached via symlink", func(t *testing.T) {
+		rootLink := filepath.Join(base, "root-link")
+		require.NoError(t, os.Symlink(root, rootLink))
+		res, err := SafeJoinResolved(rootLink, "abs-inside/file.txt")
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(rootLink, "dir", "file.txt"), res)
+	})
+
+	t.Run("missing root", func(t *testing.T) {
+		_, err := SafeJoinResolved(filepath.Join(base, "missing"), "file.txt")
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,190 @@
+package fileutils
+
+import (
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+//go:generate enum -type=sanitizeProfile -path=enum
+
+// sanitizeProfile is the platform whose file naming rules sanitization follows
+//
+//nolint:unused // This type is used by the enum generator
+type sanitizeProfile int
+
+// Sanitize profiles
+//
+//nolint:unused // These constants are used by the enum generator
+const (
+	sanitizeProfilePOSIX sanitizeProfile = iota + 1
+	sanitizeProfileWindows
+	sanitizeProfileMacOS
+	sanitizeProfilePortable
+)
+
+// nameRules describes what a file name may look like on a target platform
+type nameRules struct {
+	invalid    func(r rune) bool // characters to replace
+	reserved   bool              // windows device names like CON or COM1 are not allowed
+	trimSuffix string            // characters a name can't end with
+	noDash     bool              // a name can't start with "-", which makes it look like an option
+	maxName    int               // maximum length of a path element, in bytes
+	maxPath    int               // maximum length of a path, in bytes
+}
+
+// sanitizeRules holds the naming rules for every profile.
+// Control characters are replaced by all of them, even where allowed, as no tool copes with such names.
+var sanitizeRules = map[enum.SanitizeProfile]nameRules{
+	enum.SanitizeProfilePOSIX: {
+		invalid: func(r rune) bool { return r == '/' || unicode.IsControl(r) },
+		maxName: 255,
+		maxPath: 4096,
+	},
+	enum.SanitizeProfileWindows: {
+		invalid:    func(r rune) bool { return strings.ContainsRune(`<>:"/\|?*`, r) || unicode.IsControl(r) },
+		reserved:   true,
+		trimSuffix: ". ",
+		maxName:    255,
+		maxPath:    260,
+	},
+	enum.SanitizeProfileMacOS: {
+		invalid: func(r rune) bool { return r == '/' || r == ':' || unicode.IsControl(r) },
+		maxName: 255,
+		maxPath: 1024,
+	},
+	enum.SanitizeProfilePortable: {
+		// POSIX portable filename character set, which is safe in URLs as well
+		invalid: func(r rune) bool {
+			return (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '.' && r != '_' && r != '-'
+		},
+		reserved:   true,
+		trimSuffix: ".",
+		noDash:     true,
+		maxName:    255,
+		maxPath:    1024,
+	},
+}
+
+// windowsReservedNames are device names windows doesn't allow as a file name, with or without extension
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true, "CONIN$": true, "CONOUT$": true,
+	"COM0": true, "COM1": true, "COM2": true, "COM3": true, "COM4": true,
+	"COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"COM¹": true, "COM²": true, "COM³": true,
+	"LPT0": true, "LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true,
+	"LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+	"LPT¹": true, "LPT²": true, "LPT³": true,
+}
+
+// SanitizeFilename returns name turned into a valid file name under the rules of the given platform profile.
+// Runs of invalid characters, path separators included, are replaced by "_", as are invalid UTF-8 sequences.
+// Names the platform reserves get a "_" prefix, and a name too long is cut at a rune boundary,
+// keeping its extension. The result is never empty, "." or "..".
+// An unknown profile is treated as SanitizeProfilePOSIX.
+func SanitizeFilename(name string, profile enum.SanitizeProfile) string {
+	return sanitizeName(name, rulesFor(profile))
+}
+
+// SanitizePathFor is SanitizeFilename for a slash-separated path, sanitizing every element of it.
+// Backslashes are treated as separators, "." and empty elements are dropped, and ".." elements are kept,
+// so use SafeJoin to keep the result within a directory. A path too long is cut at a rune boundary.
+func SanitizePathFor(p string, profile enum.SanitizeProfile) string {
+	rules := rulesFor(profile)
+	p = strings.ReplaceAll(strings.TrimSpace(p), `\`, "/")
+
+	p = path.Clean(p)
+	if p == "/" {
+		return p
+	}
+
+	elems := strings.Split(p, "/")
+	res := make([]string, 0, len(elems))
+	for i, elem := range elems {
+		switch {
+		case elem == "" && i == 0:
+			res = append(res, "") // keep the leading slash of an absolute path
+		case elem == "." || elem == "..":
+			res = append(res, elem) // Clean leaves "." only for an empty path, and ".." only where needed
+		default:
+			res = append(res, sanitizeName(elem, rules))
+		}
+	}
+
+	s := strings.Join(res, "/")
+	if len(s) > rules.maxPath {
+		s = strings.TrimRight(truncateUTF8(s, rules.maxPath), "/"+rules.trimSuffix)
+	}
+	return s
+}
+
+// rulesFor returns the naming rules of profile, defaulting to POSIX
+func rulesFor(profile enum.SanitizeProfile) nameRules {
+	if rules, ok := sanitizeRules[profile]; ok {
+		return rules
+	}
+	return sanitizeRules[enum.SanitizeProfilePOSIX]
+}
+
+// sanitizeName sanitizes a single path element
+func sanitizeName(name string, rules nameRules) string {
+	name = strings.TrimSpace(strings.ToValidUTF8(name, "_"))
+
+	var sb strings.Builder
+	replaced := false // collapse runs of invalid characters into a single "_"
+	for _, r := range name {
+		if rules.invalid(r) || r == '/' {
+			if !replaced {
+				sb.WriteByte('_')
+			}
+			replaced = true
+			continue
+		}
+		sb.WriteRune(r)
+		replaced = false
+	}
+	name = strings.TrimRight(sb.String(), rules.trimSuffix)
+
+	if rules.noDash && strings.HasPrefix(name, "-") {
+		name = "_" + name[1:]
+	}
+	if rules.reserved && isWindowsReserved(name) {
+		name = "_" + name
+	}
+	if name == "" || name == "." || name == ".." {
+		name = "_" + name
+	}
+
+	if len(name) > rules.maxName {
+		// cut the stem and keep the extension, unless it's so long it's likely no extension at all
+		ext := path.Ext(name)
+		if len(ext) > rules.maxName/4 {
+			ext = ""
+		}
+		name = strings.TrimRight(truncateUTF8(name[:len(name)-len(ext)], rules.maxName-len(ext)), rules.trimSuffix) + ext
+	}
+	return name
+}
+
+// isWindowsReserved checks if name is a windows device name, which applies to its part before the first dot,
+// ignoring case and trailing spaces
+func isWindowsReserved(name string) bool {
+	if pos := strings.IndexByte(name, '.'); pos != -1 {
+		name = name[:pos]
+	}
+	return windowsReservedNames[strings.ToUpper(strings.TrimRight(name, " "))]
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a multi-byte rune
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,114 @@
+package fileutils
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/go-pkgz/fileutils/enum"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	tbl := []struct {
+		profile enum.SanitizeProfile
+		inp     string
+		out     string
+	}{
+		{enum.SanitizeProfilePOSIX, "file.txt", "file.txt"},
+		{enum.SanitizeProfilePOSIX, "  a:b<c>?.txt ", "a:b<c>?.txt"},
+		{enum.SanitizeProfilePOSIX, "dir/file.txt", "dir_file.txt"},
+		{enum.SanitizeProfilePOSIX, "line\nbreak\x00\x01.txt", "line_break_.txt"},
+		{enum.SanitizeProfilePOSIX, "CON", "CON"},
+		{enum.SanitizeProfilePOSIX, "", "_"},
+		{enum.SanitizeProfilePOSIX, "..", "_.."},
+		{enum.SanitizeProfilePOSIX, "bad\xffutf8", "bad_utf8"},
+
+		{enum.SanitizeProfileWindows, `a:b<c>?"d|e*f\g.txt`, "a_b_c_d_e_f_g.txt"},
+		{enum.SanitizeProfileWindows, "CON", "_CON"},
+		{enum.SanitizeProfileWindows, "con.txt", "_con.txt"},
+		{enum.SanitizeProfileWindows, "Com1.tar.gz", "_Com1.tar.gz"},
+		{enum.SanitizeProfileWindows, "LPT¹", "_LPT¹"},
+		{enum.SanitizeProfileWindows, "console.txt", "console.txt"},
+		{enum.SanitizeProfileWindows, "name. . .", "name"},
+		{enum.SanitizeProfileWindows, "...", "_"},
+		{enum.SanitizeProfileWindows, "tab\there", "tab_here"},
+		{enum.SanitizeProfileWindows, "привет.txt", "привет.txt"},
+
+		{enum.SanitizeProfileMacOS, "a:b.txt", "a_b.txt"},
+		{enum.SanitizeProfileMacOS, `a\b<c>.txt`, `a\b<c>.txt`},
+
+		{enum.SanitizeProfilePortable, "my file (1).txt", "my_file_1_.txt"},
+		{enum.SanitizeProfilePortable, "-rf", "_rf"},
+		{enum.SanitizeProfilePortable, "привет.txt", "_.txt"},
+		{enum.SanitizeProfilePortable, "nul.", "_nul"},
+		{enum.SanitizeProfilePortable, "a-b_c.d", "a-b_c.d"},
+
+		{enum.SanitizeProfile{}, "a/b:c", "a_b:c"}, // unknown profile falls back to POSIX
+	}
+
+	for i, tt := range tbl {
+		tt := tt
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert.Equal(t, tt.out, SanitizeFilename(tt.inp, tt.profile))
+		})
+	}
+
+	t.Run("long names", func(t *testing.T) {
+		for _, profile := range enum.SanitizeProfileValues() {
+			// multi-byte runes at the cut must not be split, and the extension has to survive
+			res := SanitizeFilename(strings.Repeat("ж", 200)+".txt", profile)
+			assert.LessOrEqual(t, len(res), 255, profile.String())
+			assert.True(t, utf8.ValidString(res), profile.String())
+			assert.True(t, strings.HasSuffix(res, ".txt"), profile.String())
+
+			res = SanitizeFilename(strings.Repeat("a", 300), profile)
+			assert.Equal(t, strings.Repeat("a", 255), res, profile.String())
+		}
+
+		// an overlong "extension" isn't kept
+		res := SanitizeFilename("a."+strings.Repeat("b", 300), enum.SanitizeProfilePOSIX)
+		assert.Equal(t, "a."+strings.Repeat("b", 253), res)
+	})
+}
+
+func TestSanitizePathFor(t *testing.T) {
+	tbl := []struct {
+		profile enum.SanitizeProfile
+		inp     string
+		out     string
+	}{
+		{enum.SanitizeProfilePOSIX, "path/to/file.txt", "path/to/file.txt"},
+		{enum.SanitizeProfilePOSIX, "/abs//path/./file.txt", "/abs/path/file.txt"},
+		{enum.SanitizeProfilePOSIX, `path\to\file.txt`, "path/to/file.txt"},
+		{enum.SanitizeProfilePOSIX, "../a/../../b", "../../b"},
+		{enum.SanitizeProfilePOSIX, "/", "/"},
+		{enum.SanitizeProfilePOSIX, "", "."},
+		{enum.SanitizeProfileWindows, "docs/CON/report?.txt", "docs/_CON/report_.txt"},
+		{enum.SanitizeProfileWindows, "dir. /file.", "dir/file"},
+		{enum.SanitizeProfilePortable, "My Docs/résumé.pdf", "My_Docs/r_sum_.pdf"},
+	}
+
+	for i, tt := range tbl {
+		tt := tt
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			assert.Equal(t, tt.out, SanitizePathFor(tt.inp, tt.profile))
+		})
+	}
+
+	t.Run("long path", func(t *testing.T) {
+		elem := strings.Repeat("ж", 100) // 200 bytes
+		long := strings.Repeat(elem+"/", 30)
+
+		res := SanitizePathFor(long, enum.SanitizeProfileWindows)
+		assert.LessOrEqual(t, len(res), 260)
+		assert.True(t, utf8.ValidString(res))
+		assert.False(t, strings.HasSuffix(res, "/"))
+
+		res = SanitizePathFor(long, enum.SanitizeProfilePOSIX)
+		assert.LessOrEqual(t, len(res), 4096)
+		assert.True(t, utf8.ValidString(res))
+	})
+}
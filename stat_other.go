@@ -0,0 +1,49 @@
+//go:build !unix
+
+package fileutils
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// fileOwner reports no owner, files have no numeric owner outside of unix
+func fileOwner(os.FileInfo) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
+
+// fileTimes returns the modification time of path for both times, the access time is not available here
+func fileTimes(path string, noFollow bool) (atime, mtime time.Time, err error) {
+	stat := os.Stat
+	if noFollow {
+		stat = os.Lstat
+	}
+	info, err := stat(path)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return info.ModTime(), info.ModTime(), nil
+}
+
+// setFileTimes sets the access and modification times of path, symlinks are always followed here
+func setFileTimes(path string, atime, mtime time.Time, noFollow bool) error {
+	if noFollow {
+		return errors.New("changing the times of a symlink is not supported on this platform")
+	}
+	return os.Chtimes(path, atime, mtime)
+}
+
+// fileStatKey returns the size and modification time of path, device, inode and change time are not available here
+func fileStatKey(path string) (fileKey, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileKey{}, err
+	}
+	return fileKey{Size: info.Size(), MTime: info.ModTime().UnixNano()}, nil
+}
+
+// fileInfoKey returns the size and modification time of the file described by info
+func fileInfoKey(info os.FileInfo) fileKey {
+	return fileKey{Size: info.Size(), MTime: info.ModTime().UnixNano()}
+}
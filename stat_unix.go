@@ -0,0 +1,74 @@
+//go:build unix
+
+package fileutils
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// fileOwner returns the numeric owner and group of the file described by info
+func fileOwner(info os.FileInfo) (uid, gid int, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(st.Uid), int(st.Gid), true
+}
+
+// fileTimes returns the access and modification times of path, of a symlink itself if noFollow is set
+func fileTimes(path string, noFollow bool) (atime, mtime time.Time, err error) {
+	var st unix.Stat_t
+	if noFollow {
+		err = unix.Lstat(path, &st)
+	} else {
+		err = unix.Stat(path, &st)
+	}
+	if err != nil {
+		return time.Time{}, time.Time{}, &os.PathError{Op: "stat", Path: path, Err: err}
+	}
+	return time.Unix(int64(st.Atim.Sec), int64(st.Atim.Nsec)), time.Unix(int64(st.Mtim.Sec), int64(st.Mtim.Nsec)), nil
+}
+
+// setFileTimes sets the access and modification times of path with utimensat,
+// which can change the times of a symlink itself if noFollow is set
+func setFileTimes(path string, atime, mtime time.Time, noFollow bool) error {
+	flags := 0
+	if noFollow {
+		flags = unix.AT_SYMLINK_NOFOLLOW
+	}
+	ts := []unix.Timespec{unix.NsecToTimespec(atime.UnixNano()), unix.NsecToTimespec(mtime.UnixNano())}
+	if err := unix.UtimesNanoAt(unix.AT_FDCWD, path, ts, flags); err != nil {
+		return &os.PathError{Op: "utimensat", Path: path, Err: err}
+	}
+	return nil
+}
+
+// fileStatKey returns the device, inode, size, modification and change times of path, following symlinks
+func fileStatKey(path string) (fileKey, error) {
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		return fileKey{}, &os.PathError{Op: "stat", Path: path, Err: err}
+	}
+	return fileKey{
+		Dev:   uint64(st.Dev), //nolint:unconvert // the type differs between platforms
+		Ino:   uint64(st.Ino), //nolint:unconvert // the type differs between platforms
+		Size:  st.Size,
+		MTime: time.Unix(int64(st.Mtim.Sec), int64(st.Mtim.Nsec)).UnixNano(),
+		CTime: time.Unix(int64(st.Ctim.Sec), int64(st.Ctim.Nsec)).UnixNano(),
+	}, nil
+}
+
+// fileInfoKey returns the device, inode, size and modification time of the file described by info,
+// the change time is not set
+func fileInfoKey(info os.FileInfo) fileKey {
+	key := fileKey{Size: info.Size(), MTime: info.ModTime().UnixNano()}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		key.Dev = uint64(st.Dev) //nolint:unconvert // the type differs between platforms
+		key.Ino = uint64(st.Ino) //nolint:unconvert // the type differs between platforms
+	}
+	return key
+}